@@ -0,0 +1,189 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/notify"
+	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
+	"github.com/btschwartz12/isza/webhook"
+)
+
+// checkInterval is how often the monitor checks the queue.
+const checkInterval = time.Minute
+
+// Threshold is the point below which the queue is running low: a number of
+// posts, or days of coverage at the posting schedule. The zero value never
+// fires.
+type Threshold struct {
+	Posts int
+	Days  float64
+}
+
+// ParseThreshold parses a threshold such as "5" (posts) or "2d" (days). "0"
+// disables alerts.
+func ParseThreshold(s string) (Threshold, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return Threshold{}, fmt.Errorf("invalid queue threshold %q", s)
+		}
+		return Threshold{Days: n}, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return Threshold{}, fmt.Errorf("invalid queue threshold %q", s)
+	}
+	return Threshold{Posts: n}, nil
+}
+
+func (t Threshold) String() string {
+	switch {
+	case t.Days > 0:
+		return fmt.Sprintf("%g days", t.Days)
+	case t.Posts > 0:
+		return fmt.Sprintf("%d posts", t.Posts)
+	default:
+		return "disabled"
+	}
+}
+
+// QueueStatus is how far the ordered queue reaches.
+type QueueStatus struct {
+	Queued int `json:"queued"`
+	// Held counts queued posts that blackouts keep from being placed on the
+	// schedule. They are left out of Queued and the coverage.
+	Held int `json:"held"`
+	// CoveredUntil is when the last queued post is expected to go out, or
+	// the time of the check if the queue is empty.
+	CoveredUntil time.Time `json:"covered_until"`
+	CoverageDays float64   `json:"coverage_days"`
+	Threshold    string    `json:"threshold"`
+	Low          bool      `json:"low"`
+	Message      string    `json:"message"`
+}
+
+// Monitor watches the queue and alerts when it falls below the threshold,
+// and again when it recovers.
+type Monitor struct {
+	logger    *zap.SugaredLogger
+	rpo       *repo.Repo
+	sched     *schedule.Schedule
+	hooks     *webhook.Dispatcher
+	notifier  *notify.Notifier
+	threshold Threshold
+
+	mu  sync.Mutex
+	low bool
+}
+
+func New(
+	logger *zap.SugaredLogger,
+	rpo *repo.Repo,
+	sched *schedule.Schedule,
+	hooks *webhook.Dispatcher,
+	notifier *notify.Notifier,
+	threshold Threshold,
+) *Monitor {
+	return &Monitor{
+		logger:    logger,
+		rpo:       rpo,
+		sched:     sched,
+		hooks:     hooks,
+		notifier:  notifier,
+		threshold: threshold,
+	}
+}
+
+// Status computes the current queue status.
+func (m *Monitor) Status(ctx context.Context) (*QueueStatus, error) {
+	now := time.Now().In(m.sched.Location())
+	projections, err := schedule.ProjectQueue(ctx, m.rpo, repo.QueueFeed, m.sched, now)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &QueueStatus{
+		CoveredUntil: now,
+		Threshold:    m.threshold.String(),
+	}
+	for _, p := range projections {
+		if p.Pinned {
+			continue
+		}
+		if p.PublishAt.IsZero() {
+			status.Held++
+			continue
+		}
+		status.Queued++
+		if p.PublishAt.After(status.CoveredUntil) {
+			status.CoveredUntil = p.PublishAt
+		}
+	}
+	status.CoverageDays = status.CoveredUntil.Sub(now).Hours() / 24
+
+	switch {
+	case m.threshold.Days > 0:
+		status.Low = status.CoverageDays < m.threshold.Days
+	case m.threshold.Posts > 0:
+		status.Low = status.Queued < m.threshold.Posts
+	}
+	status.Message = fmt.Sprintf("%d posts queued, covering %.1f days", status.Queued, status.CoverageDays)
+	if status.Held > 0 {
+		status.Message += fmt.Sprintf(", %d held by blackouts", status.Held)
+	}
+	if status.Low {
+		status.Message = fmt.Sprintf("Queue is running low: %s (threshold %s)", status.Message, status.Threshold)
+	}
+	return status, nil
+}
+
+// Check computes the queue status and alerts if it crossed the threshold
+// since the last check.
+func (m *Monitor) Check(ctx context.Context) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		m.logger.Errorw("error checking queue status", "error", err)
+		return
+	}
+
+	m.mu.Lock()
+	changed := status.Low != m.low
+	m.low = status.Low
+	m.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if status.Low {
+		m.logger.Warnw("queue is running low", "queued", status.Queued, "coverage_days", status.CoverageDays, "threshold", status.Threshold)
+		m.hooks.Emit(ctx, webhook.EventQueueLow, status)
+		m.notifier.QueueLow(ctx, status.Queued, status.CoveredUntil, status.Threshold)
+	} else {
+		m.logger.Infow("queue recovered", "queued", status.Queued, "coverage_days", status.CoverageDays)
+		m.hooks.Emit(ctx, webhook.EventQueueRecovered, status)
+	}
+}
+
+// Run checks the queue periodically until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	m.Check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
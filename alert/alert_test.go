@@ -0,0 +1,46 @@
+package alert
+
+import "testing"
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Threshold
+		str     string
+		wantErr bool
+	}{
+		{in: "5", want: Threshold{Posts: 5}, str: "5 posts"},
+		{in: " 12 ", want: Threshold{Posts: 12}, str: "12 posts"},
+		{in: "2d", want: Threshold{Days: 2}, str: "2 days"},
+		{in: "1.5d", want: Threshold{Days: 1.5}, str: "1.5 days"},
+		{in: "0", want: Threshold{}, str: "disabled"},
+		{in: "0d", want: Threshold{}, str: "disabled"},
+		{in: "", wantErr: true},
+		{in: "d", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "-2d", wantErr: true},
+		{in: "2.5", wantErr: true},
+		{in: "3h", wantErr: true},
+		{in: "two", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseThreshold(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseThreshold(%q) = %+v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseThreshold(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseThreshold(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if s := got.String(); s != tt.str {
+				t.Errorf("String() = %q, want %q", s, tt.str)
+			}
+		})
+	}
+}
@@ -4,3 +4,8 @@ import "embed"
 
 //go:embed templates
 var Templates embed.FS
+
+// Static holds the scripts the pages load, served under /static/js.
+//
+//go:embed static
+var Static embed.FS
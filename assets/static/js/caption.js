@@ -0,0 +1,51 @@
+// Lints the caption as it is typed on the add and edit post pages.
+
+let lintTimer;
+
+// lintCaption lints the caption with the checked hashtag sets
+// appended, as it will be published.
+function lintCaption(textarea) {
+    let setIDs = Array.from(textarea.form.querySelectorAll('input[name="hashtag_set"]:checked'))
+        .map(function(input) { return Number(input.value); });
+    clearTimeout(lintTimer);
+    lintTimer = setTimeout(function() {
+        fetch('/api/captions/lint', {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify({caption: textarea.value, hashtag_set_ids: setIDs}),
+        })
+            .then(function(resp) { return resp.json(); })
+            .then(renderLint);
+    }, 250);
+}
+
+function setCounter(id, label, count, max) {
+    let el = document.getElementById(id);
+    el.textContent = label + ': ' + count + '/' + max;
+    el.className = 'tag';
+    if (count > max) {
+        el.className += ' is-danger';
+    } else if (count >= max * 0.9) {
+        el.className += ' is-warning';
+    }
+}
+
+function renderLint(res) {
+    setCounter('caption-length', 'Characters', res.length, 2200);
+    setCounter('caption-hashtags', 'Hashtags', res.hashtags.length, 30);
+    setCounter('caption-mentions', 'Mentions', res.mentions.length, 20);
+    let list = document.getElementById('caption-issues');
+    list.innerHTML = '';
+    res.errors.forEach(function(issue) {
+        let li = document.createElement('li');
+        li.className = 'has-text-danger';
+        li.textContent = issue.message;
+        list.appendChild(li);
+    });
+    res.warnings.forEach(function(issue) {
+        let li = document.createElement('li');
+        li.className = 'has-text-warning-dark';
+        li.textContent = issue.message;
+        list.appendChild(li);
+    });
+}
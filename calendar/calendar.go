@@ -0,0 +1,147 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
+)
+
+type EventKind string
+
+const (
+	KindQueued EventKind = "queued"
+	KindPinned EventKind = "pinned"
+	KindPosted EventKind = "posted"
+
+	// eventDuration is the length given to events in the iCalendar feed.
+	eventDuration = 15 * time.Minute
+)
+
+type Event struct {
+	Post  repo.Post
+	Start time.Time
+	Kind  EventKind
+}
+
+// Summary is a one line description of the event.
+func (e *Event) Summary() string {
+	first, _, _ := strings.Cut(e.Post.Caption, "\n")
+	if len([]rune(first)) > 60 {
+		first = string([]rune(first)[:60]) + "…"
+	}
+	switch e.Kind {
+	case KindQueued:
+		return fmt.Sprintf("Queued #%d: %s", e.Post.Position, first)
+	case KindPinned:
+		return fmt.Sprintf("Pinned: %s", first)
+	default:
+		return fmt.Sprintf("Posted: %s", first)
+	}
+}
+
+// Events returns an event for every queued post at its projected publish
+// slot and every posted post at the time it went out, sorted by time. Queued
+// posts that blackouts keep from being placed are left out.
+func Events(ctx context.Context, rpo *repo.Repo, sched *schedule.Schedule, now time.Time) ([]Event, error) {
+	projections, err := schedule.ProjectQueue(ctx, rpo, repo.QueueFeed, sched, now)
+	if err != nil {
+		return nil, err
+	}
+	posted, err := rpo.GetPostsByStatus(ctx, repo.StatusPosted)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(projections)+len(posted))
+	for _, p := range projections {
+		if p.PublishAt.IsZero() {
+			continue
+		}
+		kind := KindQueued
+		if p.Pinned {
+			kind = KindPinned
+		}
+		events = append(events, Event{
+			Post:  p.Post,
+			Start: p.PublishAt,
+			Kind:  kind,
+		})
+	}
+	for _, post := range posted {
+		if !post.PostedAt.IsPresent() {
+			continue
+		}
+		events = append(events, Event{
+			Post:  post,
+			Start: post.PostedAt.MustGet().In(now.Location()),
+			Kind:  KindPosted,
+		})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events, nil
+}
+
+// WriteICS writes events as an iCalendar (RFC 5545) feed.
+func WriteICS(w io.Writer, name string, events []Event, now time.Time) error {
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:-//isza//posts//EN")
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "X-WR-CALNAME:"+escapeText(name))
+	for _, e := range events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, fmt.Sprintf("UID:post-%d@isza", e.Post.ID))
+		writeLine(&b, "DTSTAMP:"+formatTime(now))
+		writeLine(&b, "DTSTART:"+formatTime(e.Start))
+		writeLine(&b, "DTEND:"+formatTime(e.Start.Add(eventDuration)))
+		writeLine(&b, "SUMMARY:"+escapeText(e.Summary()))
+		writeLine(&b, "DESCRIPTION:"+escapeText(e.Post.Caption))
+		writeLine(&b, "CATEGORIES:"+strings.ToUpper(string(e.Kind)))
+		writeLine(&b, "END:VEVENT")
+	}
+	writeLine(&b, "END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// writeLine writes a content line, folding it so that no line is longer than
+// 75 octets. Continuation lines start with a space.
+func writeLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
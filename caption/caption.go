@@ -0,0 +1,183 @@
+package caption
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits enforced by Instagram when publishing a post.
+const (
+	MaxLength   = 2200
+	MaxHashtags = 30
+	MaxMentions = 20
+
+	// previewLength is roughly how much of a caption is shown in the feed
+	// before it is truncated behind "more".
+	previewLength = 125
+	// warnRatio is the fraction of a limit at which a warning is raised.
+	warnRatio = 0.9
+)
+
+var (
+	hashtagRe = regexp.MustCompile(`#[\p{L}\p{N}_]+`)
+	// mentionRe matches a mention in its first group. The @ must start the
+	// caption or follow a character that is not part of a word, so email
+	// addresses are not taken for mentions.
+	mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])(@[A-Za-z0-9._]+)`)
+
+	ErrInvalidCaption = errors.New("invalid caption")
+)
+
+type Issue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Result struct {
+	Length   int      `json:"length"`
+	Hashtags []string `json:"hashtags"`
+	Mentions []string `json:"mentions"`
+	Errors   []Issue  `json:"errors"`
+	Warnings []Issue  `json:"warnings"`
+}
+
+// Valid reports whether the caption can be published.
+func (r *Result) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Err returns an error wrapping ErrInvalidCaption describing every error
+// found, or nil if the caption is valid.
+func (r *Result) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	msgs := make([]string, len(r.Errors))
+	for i, issue := range r.Errors {
+		msgs[i] = issue.Message
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidCaption, strings.Join(msgs, "; "))
+}
+
+func (r *Result) addError(code, format string, a ...any) {
+	r.Errors = append(r.Errors, Issue{Code: code, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *Result) addWarning(code, format string, a ...any) {
+	r.Warnings = append(r.Warnings, Issue{Code: code, Message: fmt.Sprintf(format, a...)})
+}
+
+// Lint checks a caption against Instagram's limits.
+func Lint(caption string) *Result {
+	r := &Result{
+		Length:   utf8.RuneCountInString(caption),
+		Hashtags: hashtagRe.FindAllString(caption, -1),
+		Mentions: mentions(caption),
+		Errors:   []Issue{},
+		Warnings: []Issue{},
+	}
+	if r.Hashtags == nil {
+		r.Hashtags = []string{}
+	}
+	if r.Mentions == nil {
+		r.Mentions = []string{}
+	}
+
+	if strings.TrimSpace(caption) == "" {
+		r.addError("empty", "caption is empty")
+	}
+	if _, bad := placeholders(caption); bad != "" {
+		r.addError("invalid_placeholder", "invalid placeholder %q", bad)
+	}
+
+	checkLimit(r, "too_long", "characters", r.Length, MaxLength)
+	checkLimit(r, "too_many_hashtags", "hashtags", len(r.Hashtags), MaxHashtags)
+	checkLimit(r, "too_many_mentions", "mentions", len(r.Mentions), MaxMentions)
+
+	if dupes := duplicates(r.Hashtags); len(dupes) > 0 {
+		r.addWarning("duplicate_hashtags", "duplicate hashtags: %s", strings.Join(dupes, ", "))
+	}
+	if r.Length > previewLength {
+		r.addWarning("truncated", "only the first %d characters are shown before \"more\"", previewLength)
+	}
+	return r
+}
+
+func mentions(caption string) []string {
+	var found []string
+	for _, m := range mentionRe.FindAllStringSubmatch(caption, -1) {
+		found = append(found, m[1])
+	}
+	return found
+}
+
+func checkLimit(r *Result, code, noun string, count, max int) {
+	if count > max {
+		r.addError(code, "%d %s exceeds the limit of %d", count, noun, max)
+	} else if float64(count) >= float64(max)*warnRatio {
+		r.addWarning("near_"+code, "%d %s is close to the limit of %d", count, noun, max)
+	}
+}
+
+func duplicates(tags []string) []string {
+	seen := make(map[string]bool)
+	var dupes []string
+	for _, tag := range tags {
+		key := strings.ToLower(tag)
+		if seen[key] {
+			dupes = append(dupes, tag)
+			continue
+		}
+		seen[key] = true
+	}
+	return dupes
+}
+
+// ParseHashtags splits a comma or whitespace separated list of hashtags,
+// adding a leading '#' where missing and dropping duplicates.
+func ParseHashtags(s string) ([]string, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	seen := make(map[string]bool)
+	tags := make([]string, 0, len(fields))
+	for _, field := range fields {
+		tag := "#" + strings.TrimLeft(field, "#")
+		if hashtagRe.FindString(tag) != tag {
+			return nil, fmt.Errorf("invalid hashtag %q", field)
+		}
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// AppendHashtags appends the given hashtags to the caption on a new
+// paragraph, skipping any that the caption already contains.
+func AppendHashtags(caption string, tags []string) string {
+	present := make(map[string]bool)
+	for _, tag := range hashtagRe.FindAllString(caption, -1) {
+		present[strings.ToLower(tag)] = true
+	}
+	var missing []string
+	for _, tag := range tags {
+		key := strings.ToLower(tag)
+		if present[key] {
+			continue
+		}
+		present[key] = true
+		missing = append(missing, tag)
+	}
+	if len(missing) == 0 {
+		return caption
+	}
+	return strings.TrimRight(caption, " \n") + "\n\n" + strings.Join(missing, " ")
+}
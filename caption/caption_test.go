@@ -0,0 +1,142 @@
+package caption
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func hashtags(n int) string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = "#tag" + strings.Repeat("x", i)
+	}
+	return strings.Join(tags, " ")
+}
+
+func mentionList(n int) string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = "@user" + strings.Repeat("x", i)
+	}
+	return strings.Join(names, " ")
+}
+
+func codes(issues []Issue) []string {
+	codes := make([]string, len(issues))
+	for i, issue := range issues {
+		codes[i] = issue.Code
+	}
+	return codes
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		caption  string
+		hashtags []string
+		mentions []string
+		errors   []string
+		warnings []string
+	}{
+		{
+			name:     "plain",
+			caption:  "Sunset over the bay #sunset @friend",
+			hashtags: []string{"#sunset"},
+			mentions: []string{"@friend"},
+		},
+		{
+			name:    "empty",
+			caption: "  \n",
+			errors:  []string{"empty"},
+		},
+		{
+			name:     "unicode hashtag",
+			caption:  "Café #café_2024",
+			hashtags: []string{"#café_2024"},
+		},
+		{
+			name:     "email is not a mention",
+			caption:  "Write to me@example.com or @shop.owner",
+			mentions: []string{"@shop.owner"},
+		},
+		{
+			name:     "mentions after punctuation",
+			caption:  "@first (@second),@third",
+			mentions: []string{"@first", "@second", "@third"},
+		},
+		{
+			name:    "invalid placeholder",
+			caption: "Hello {{Not Valid}}",
+			errors:  []string{"invalid_placeholder"},
+		},
+		{
+			name:    "valid placeholder",
+			caption: "Day {{ post_count }}",
+		},
+		{
+			name:     "too many hashtags",
+			caption:  hashtags(MaxHashtags + 1),
+			errors:   []string{"too_many_hashtags"},
+			warnings: []string{"truncated"},
+		},
+		{
+			name:     "near hashtag limit",
+			caption:  hashtags(27),
+			warnings: []string{"near_too_many_hashtags", "truncated"},
+		},
+		{
+			name:     "too many mentions",
+			caption:  mentionList(MaxMentions + 1),
+			errors:   []string{"too_many_mentions"},
+			warnings: []string{"truncated"},
+		},
+		{
+			name:     "too long",
+			caption:  strings.Repeat("a", MaxLength+1),
+			errors:   []string{"too_long"},
+			warnings: []string{"truncated"},
+		},
+		{
+			name:     "near length limit",
+			caption:  strings.Repeat("é", 1980),
+			warnings: []string{"near_too_long", "truncated"},
+		},
+		{
+			name:     "duplicate hashtags",
+			caption:  "#Beach #beach #sea",
+			hashtags: []string{"#Beach", "#beach", "#sea"},
+			warnings: []string{"duplicate_hashtags"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Lint(tt.caption)
+			if tt.hashtags != nil && !slices.Equal(res.Hashtags, tt.hashtags) {
+				t.Errorf("hashtags = %q, want %q", res.Hashtags, tt.hashtags)
+			}
+			if tt.mentions != nil && !slices.Equal(res.Mentions, tt.mentions) {
+				t.Errorf("mentions = %q, want %q", res.Mentions, tt.mentions)
+			}
+			if got, want := codes(res.Errors), tt.errors; !slices.Equal(got, want) {
+				t.Errorf("errors = %q, want %q", got, want)
+			}
+			if got, want := codes(res.Warnings), tt.warnings; !slices.Equal(got, want) {
+				t.Errorf("warnings = %q, want %q", got, want)
+			}
+			if res.Valid() != (len(tt.errors) == 0) {
+				t.Errorf("Valid() = %v with errors %q", res.Valid(), tt.errors)
+			}
+			if err := res.Err(); (err == nil) != res.Valid() || err != nil && !errors.Is(err, ErrInvalidCaption) {
+				t.Errorf("Err() = %v", err)
+			}
+		})
+	}
+}
+
+func TestLintCountsCharacters(t *testing.T) {
+	if got := Lint("héllo 👋").Length; got != 7 {
+		t.Errorf("Length = %d, want 7", got)
+	}
+}
@@ -0,0 +1,59 @@
+package caption
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	placeholderRe = regexp.MustCompile(`\{\{(.*?)\}\}`)
+	variableRe    = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// Variables returns the names of the variables referenced by a caption
+// template. Placeholders take the form {{name}}; anything else between
+// double braces is rejected.
+func Variables(text string) ([]string, error) {
+	names, bad := placeholders(text)
+	if bad != "" {
+		return nil, fmt.Errorf("%w: invalid placeholder %q", ErrInvalidCaption, bad)
+	}
+	return names, nil
+}
+
+// placeholders returns the variable names referenced by text, or the first
+// malformed placeholder it contains.
+func placeholders(text string) ([]string, string) {
+	var names []string
+	for _, m := range placeholderRe.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimSpace(m[1])
+		if !variableRe.MatchString(name) {
+			return nil, m[0]
+		}
+		names = append(names, name)
+	}
+	return names, ""
+}
+
+// Render substitutes every {{name}} placeholder in the caption with its
+// value from vars. Referencing a variable that is not defined is an error.
+func Render(text string, vars map[string]string) (string, error) {
+	names, err := Variables(text)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if _, ok := vars[name]; !ok {
+			return "", fmt.Errorf("%w: unknown variable %q", ErrInvalidCaption, name)
+		}
+	}
+	return placeholderRe.ReplaceAllStringFunc(text, func(m string) string {
+		return vars[strings.TrimSpace(m[2:len(m)-2])]
+	}), nil
+}
+
+// ValidVariableName reports whether name can be used as a caption variable.
+func ValidVariableName(name string) bool {
+	return variableRe.MatchString(name)
+}
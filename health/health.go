@@ -0,0 +1,172 @@
+// Package health checks whether isza and the things it depends on are
+// working.
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btschwartz12/isza/repo"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	// checkTimeout bounds each check.
+	checkTimeout = 10 * time.Second
+	// pythonCacheTTL is how long the result of the python check is reused,
+	// since importing instagrapi takes a while and rarely changes.
+	pythonCacheTTL = time.Minute
+)
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all checks. Status is ok only if every check is.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Checker runs the readiness checks.
+type Checker struct {
+	rpo             *repo.Repo
+	instaWorkingDir string
+
+	mu           sync.Mutex
+	pythonResult *CheckResult
+	pythonAt     time.Time
+}
+
+func New(rpo *repo.Repo, instaWorkingDir string) *Checker {
+	return &Checker{
+		rpo:             rpo,
+		instaWorkingDir: instaWorkingDir,
+	}
+}
+
+type check struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Check runs every check concurrently and reports their results in a fixed
+// order.
+func (c *Checker) Check(ctx context.Context) Report {
+	checks := []check{
+		{"database", c.rpo.Ping},
+		{"var_dir", func(context.Context) error { return writable(c.rpo.VarDir()) }},
+		{"posts_dir", func(context.Context) error { return writable(c.rpo.PostsDir()) }},
+		{"post_script", c.checkPostScript},
+	}
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make([]CheckResult, len(checks)+1),
+	}
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, chk)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		report.Checks[len(checks)] = c.checkPython(ctx)
+	}()
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, chk check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	start := time.Now()
+	err := chk.run(ctx)
+	result := CheckResult{
+		Name:      chk.name,
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// writable checks that a file can be created in dir.
+func writable(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+func (c *Checker) checkPostScript(context.Context) error {
+	info, err := os.Stat(filepath.Join(c.instaWorkingDir, "post.py"))
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("post.py is not a regular file")
+	}
+	return nil
+}
+
+// checkPython checks that python3 can import instagrapi from the working
+// dir, the way the post script is run. The result is cached for
+// pythonCacheTTL.
+func (c *Checker) checkPython(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pythonResult != nil && time.Since(c.pythonAt) < pythonCacheTTL {
+		return *c.pythonResult
+	}
+	result := run(ctx, check{"python", func(ctx context.Context) error {
+		cmd := exec.CommandContext(ctx, "python3", "-c", "import instagrapi")
+		cmd.Dir = c.instaWorkingDir
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := lastLine(stderr.String()); msg != "" {
+				return fmt.Errorf("%w: %s", err, msg)
+			}
+			return err
+		}
+		return nil
+	}})
+	c.pythonResult = &result
+	c.pythonAt = time.Now()
+	return result
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,271 @@
+// Package idempotency makes retried requests safe. A request that repeats the
+// Idempotency-Key header of an earlier one is not run again; the response of
+// the earlier one is replayed instead.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/logging"
+	"github.com/btschwartz12/isza/repo"
+)
+
+const (
+	// KeyHeader is the request header carrying the idempotency key.
+	KeyHeader = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Middleware stores the response of each mutating request that carries an
+// Idempotency-Key header for window, and replays it for later requests with
+// the same key. A repeat that differs from the original request gets a 422,
+// and one made while the original is still in progress gets a 409. Server
+// errors are not stored, so the request can be retried once they are fixed,
+// unless the handler called Commit first: a retry of a publish that failed
+// after claiming its post would publish the next one. Bodies over maxBody
+// bytes get a 413.
+//
+// Requests without the header, and reads, pass straight through.
+func Middleware(logger *zap.SugaredLogger, rpo *repo.Repo, window time.Duration, maxBody int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(KeyHeader)
+			if key == "" || !mutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			l := logging.FromContext(r.Context(), logger).With("idempotency_key", key)
+			if len(key) > maxKeyLength {
+				http.Error(w, "Idempotency key is too long", http.StatusBadRequest)
+				return
+			}
+
+			body, err := spool(http.MaxBytesReader(w, r.Body, maxBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, fmt.Sprintf("Request is larger than %d MB", maxBody>>20), http.StatusRequestEntityTooLarge)
+					return
+				}
+				l.Errorw("error reading request body", "error", err)
+				http.Error(w, "Error reading request body", http.StatusBadRequest)
+				return
+			}
+			defer body.remove()
+			fp, err := fingerprint(r, body)
+			if err != nil {
+				l.Errorw("error fingerprinting request", "error", err)
+				http.Error(w, "Error reading request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = body
+
+			now := time.Now()
+			existing, reserved, err := rpo.ReserveIdempotencyKey(r.Context(), key, fp, now, now.Add(window))
+			if err != nil {
+				l.Errorw("error reserving idempotency key", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !reserved {
+				switch {
+				case existing.Fingerprint != fp:
+					http.Error(w, "Idempotency key was already used for a different request", http.StatusUnprocessableEntity)
+				case !existing.Completed():
+					http.Error(w, "A request with this idempotency key is in progress", http.StatusConflict)
+				default:
+					l.Infow("replaying response", "status", existing.StatusCode)
+					for k, v := range existing.Header {
+						w.Header()[k] = v
+					}
+					w.Header().Set(ReplayedHeader, "true")
+					w.WriteHeader(existing.StatusCode)
+					w.Write(existing.Body)
+				}
+				return
+			}
+
+			// The response is stored even if the client has gone away, since
+			// the request has had its effect. If the handler panics or fails
+			// with a server error before committing, the key is released so
+			// that the request can be retried.
+			ctx := context.WithoutCancel(r.Context())
+			stored, committed := false, new(atomic.Bool)
+			r = r.WithContext(context.WithValue(r.Context(), committedKey{}, committed))
+			defer func() {
+				if !stored && !committed.Load() {
+					if err := rpo.ReleaseIdempotencyKey(ctx, key); err != nil {
+						l.Errorw("error releasing idempotency key", "error", err)
+					}
+				}
+			}()
+			rec := &recorder{header: http.Header{}}
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			// If the response cannot be stored, the key is left in progress
+			// rather than released, so a retry cannot repeat the request.
+			if rec.status < http.StatusInternalServerError || committed.Load() {
+				if err := rpo.CompleteIdempotencyKey(ctx, key, rec.status, rec.header, rec.body.Bytes()); err != nil {
+					l.Errorw("error storing idempotent response", "error", err)
+				}
+				stored = true
+			}
+
+			for k, v := range rec.header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+		})
+	}
+}
+
+type committedKey struct{}
+
+// Commit records that the request carrying ctx has had an effect that a
+// retry must not repeat, so its response is stored even if it is a server
+// error. It does nothing outside the middleware.
+func Commit(ctx context.Context) {
+	if committed, ok := ctx.Value(committedKey{}).(*atomic.Bool); ok {
+		committed.Store(true)
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// recorder buffers a response so that it can be stored before it is sent.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+// spooledBody is a request body copied to a temporary file, so that it can be
+// read once to fingerprint it and again by the handler. Uploads may be too
+// large to hold in memory.
+type spooledBody struct {
+	*os.File
+}
+
+func spool(body io.Reader) (*spooledBody, error) {
+	f, err := os.CreateTemp("", "isza-request-*")
+	if err != nil {
+		return nil, err
+	}
+	b := &spooledBody{f}
+	if _, err := io.Copy(f, body); err != nil {
+		b.remove()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		b.remove()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Close leaves the file open for remove, since handlers may close the body.
+func (b *spooledBody) Close() error {
+	return nil
+}
+
+func (b *spooledBody) remove() {
+	b.File.Close()
+	os.Remove(b.Name())
+}
+
+// fingerprint hashes what makes a request the same as another: its method,
+// path, query and body. Multipart bodies are hashed part by part, since
+// clients pick a new boundary each time they send one. A form already parsed
+// by an earlier handler, such as the token check, is hashed instead of the
+// body it consumed.
+func fingerprint(r *http.Request, body *spooledBody) (string, error) {
+	h := sha256.New()
+	writeField(h, []byte(r.Method))
+	writeField(h, []byte(r.URL.Path))
+	writeField(h, []byte(r.URL.RawQuery))
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case len(r.PostForm) > 0:
+		writeField(h, []byte(r.PostForm.Encode()))
+	case strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "":
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			writeField(h, []byte(part.FormName()))
+			writeField(h, []byte(part.FileName()))
+			content := sha256.New()
+			if _, err := io.Copy(content, part); err != nil {
+				return "", err
+			}
+			writeField(h, content.Sum(nil))
+		}
+	default:
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeField writes b length-prefixed, so that fields cannot run into each
+// other.
+func writeField(h hash.Hash, b []byte) {
+	binary.Write(h, binary.BigEndian, uint64(len(b)))
+	h.Write(b)
+}
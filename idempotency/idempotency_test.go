@@ -0,0 +1,303 @@
+package idempotency
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/repo"
+)
+
+type part struct {
+	field, filename, content string
+}
+
+func multipartRequest(t *testing.T, boundary string, parts ...part) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.SetBoundary(boundary); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range parts {
+		var w io.Writer
+		var err error
+		if p.filename != "" {
+			w, err = mw.CreateFormFile(p.field, p.filename)
+		} else {
+			w, err = mw.CreateFormField(p.field)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, p.content)
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/post", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func jsonRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func formRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/post/1/edit", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// parsedFormRequest is a form request whose body an earlier handler has
+// already consumed.
+func parsedFormRequest(t *testing.T, form url.Values) *http.Request {
+	t.Helper()
+	r := formRequest(form)
+	if err := r.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// fingerprintOf fingerprints r and checks that its body can still be read in
+// full afterwards.
+func fingerprintOf(t *testing.T, r *http.Request) string {
+	t.Helper()
+	want, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := spool(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.remove()
+	fp, err := fingerprint(r, body)
+	if err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+	got, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("body after fingerprint = %q, want %q", got, want)
+	}
+	return fp
+}
+
+func TestFingerprint(t *testing.T) {
+	photo := part{"files", "photo.png", "png bytes"}
+	caption := part{"caption", "", "hello"}
+
+	tests := []struct {
+		name string
+		a, b func(t *testing.T) *http.Request
+		same bool
+	}{
+		{
+			name: "same json",
+			a: func(*testing.T) *http.Request {
+				return jsonRequest(http.MethodPost, "/api/posts/1/status", `{"status":"queued"}`)
+			},
+			b: func(*testing.T) *http.Request {
+				return jsonRequest(http.MethodPost, "/api/posts/1/status", `{"status":"queued"}`)
+			},
+			same: true,
+		},
+		{
+			name: "different json",
+			a: func(*testing.T) *http.Request {
+				return jsonRequest(http.MethodPost, "/api/posts/1/status", `{"status":"queued"}`)
+			},
+			b: func(*testing.T) *http.Request {
+				return jsonRequest(http.MethodPost, "/api/posts/1/status", `{"status":"draft"}`)
+			},
+		},
+		{
+			name: "different method",
+			a:    func(*testing.T) *http.Request { return jsonRequest(http.MethodPost, "/api/posts/1", `{}`) },
+			b:    func(*testing.T) *http.Request { return jsonRequest(http.MethodPut, "/api/posts/1", `{}`) },
+		},
+		{
+			name: "different path",
+			a:    func(*testing.T) *http.Request { return jsonRequest(http.MethodPost, "/api/posts/1/pin", `{}`) },
+			b:    func(*testing.T) *http.Request { return jsonRequest(http.MethodPost, "/api/posts/2/pin", `{}`) },
+		},
+		{
+			name: "different query",
+			a:    func(*testing.T) *http.Request { return jsonRequest(http.MethodPost, "/api/posts?tz=UTC", `{}`) },
+			b:    func(*testing.T) *http.Request { return jsonRequest(http.MethodPost, "/api/posts?tz=EST", `{}`) },
+		},
+		{
+			name: "path and query do not run together",
+			a:    func(*testing.T) *http.Request { return jsonRequest(http.MethodPost, "/api/ab", `{}`) },
+			b:    func(*testing.T) *http.Request { return jsonRequest(http.MethodPost, "/api/a?b", `{}`) },
+		},
+		{
+			name: "multipart with new boundary",
+			a:    func(t *testing.T) *http.Request { return multipartRequest(t, "boundary1", caption, photo) },
+			b:    func(t *testing.T) *http.Request { return multipartRequest(t, "boundary2", caption, photo) },
+			same: true,
+		},
+		{
+			name: "multipart with different file",
+			a:    func(t *testing.T) *http.Request { return multipartRequest(t, "boundary1", caption, photo) },
+			b: func(t *testing.T) *http.Request {
+				return multipartRequest(t, "boundary1", caption, part{"files", "photo.png", "other bytes"})
+			},
+		},
+		{
+			name: "multipart with renamed file",
+			a:    func(t *testing.T) *http.Request { return multipartRequest(t, "boundary1", photo) },
+			b: func(t *testing.T) *http.Request {
+				return multipartRequest(t, "boundary1", part{"files", "other.png", "png bytes"})
+			},
+		},
+		{
+			name: "multipart with reordered files",
+			a: func(t *testing.T) *http.Request {
+				return multipartRequest(t, "boundary1", photo, part{"files", "second.png", "more bytes"})
+			},
+			b: func(t *testing.T) *http.Request {
+				return multipartRequest(t, "boundary1", part{"files", "second.png", "more bytes"}, photo)
+			},
+		},
+		{
+			name: "parsed form",
+			a: func(t *testing.T) *http.Request {
+				return parsedFormRequest(t, url.Values{"caption": {"hello"}, "draft": {"on"}})
+			},
+			b: func(t *testing.T) *http.Request {
+				return parsedFormRequest(t, url.Values{"draft": {"on"}, "caption": {"hello"}})
+			},
+			same: true,
+		},
+		{
+			name: "parsed form with different value",
+			a:    func(t *testing.T) *http.Request { return parsedFormRequest(t, url.Values{"caption": {"hello"}}) },
+			b:    func(t *testing.T) *http.Request { return parsedFormRequest(t, url.Values{"caption": {"bye"}}) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := fingerprintOf(t, tt.a(t)), fingerprintOf(t, tt.b(t))
+			if (a == b) != tt.same {
+				t.Errorf("fingerprints equal = %v, want %v", a == b, tt.same)
+			}
+		})
+	}
+}
+
+func TestSpoolCloseKeepsFile(t *testing.T) {
+	body, err := spool(strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.remove()
+	// Handlers close the body; the middleware still reads it afterwards.
+	body.Close()
+	got, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("reading after Close: %v", err)
+	}
+	if string(got) != "payload" {
+		t.Errorf("body = %q, want %q", got, "payload")
+	}
+}
+
+func newRepo(t *testing.T) *repo.Repo {
+	t.Helper()
+	rpo, err := repo.NewRepo(zap.NewNop().Sugar(), t.TempDir(), time.UTC, repo.Options{MaxCarouselItems: repo.MaxCarouselItems})
+	if err != nil {
+		t.Fatalf("creating repo: %v", err)
+	}
+	return rpo
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name string
+		// status is what the handler responds with; commit makes it call
+		// Commit first.
+		status int
+		commit bool
+		// runs is how many times two identical requests run the handler.
+		runs int
+	}{
+		{name: "success is replayed", status: http.StatusCreated, runs: 1},
+		{name: "client error is replayed", status: http.StatusConflict, runs: 1},
+		{name: "server error is retried", status: http.StatusInternalServerError, runs: 2},
+		{name: "unavailable is retried", status: http.StatusServiceUnavailable, runs: 2},
+		{name: "server error after commit is replayed", status: http.StatusInternalServerError, commit: true, runs: 1},
+		{name: "timeout after commit is replayed", status: http.StatusGatewayTimeout, commit: true, runs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			handler := Middleware(zap.NewNop().Sugar(), newRepo(t), time.Hour, 1<<20)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				runs++
+				if tt.commit {
+					Commit(r.Context())
+				}
+				http.Error(w, fmt.Sprintf("run %d", runs), tt.status)
+			}))
+
+			var bodies []string
+			for i := 0; i < 2; i++ {
+				r := jsonRequest(http.MethodPost, "/api/posts/make_post", `{}`)
+				r.Header.Set(KeyHeader, "key")
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+				if w.Code != tt.status {
+					t.Fatalf("request %d status = %d, want %d", i+1, w.Code, tt.status)
+				}
+				bodies = append(bodies, w.Body.String())
+			}
+			if runs != tt.runs {
+				t.Errorf("handler ran %d times, want %d", runs, tt.runs)
+			}
+			if replayed := bodies[0] == bodies[1]; replayed != (tt.runs == 1) {
+				t.Errorf("second response %q, first %q", bodies[1], bodies[0])
+			}
+		})
+	}
+}
+
+func TestMiddlewareKeepsKeyAfterCommittedPanic(t *testing.T) {
+	rpo := newRepo(t)
+	runs := 0
+	handler := Middleware(zap.NewNop().Sugar(), rpo, time.Hour, 1<<20)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		Commit(r.Context())
+		panic("publish crashed")
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		r := jsonRequest(http.MethodPost, "/api/posts/make_post", `{}`)
+		r.Header.Set(KeyHeader, "key")
+		w := httptest.NewRecorder()
+		func() {
+			defer func() { recover() }()
+			handler.ServeHTTP(w, r)
+		}()
+		return w
+	}
+	serve()
+	if w := serve(); w.Code != http.StatusConflict {
+		t.Errorf("retry status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if runs != 1 {
+		t.Errorf("handler ran %d times, want 1", runs)
+	}
+}
@@ -0,0 +1,21 @@
+import sys
+from instagrapi import Client
+
+username = sys.argv[1]
+password = sys.argv[2]
+media_id = sys.argv[3]
+comment_file = sys.argv[4]
+
+with open(comment_file, 'r') as f:
+    text = f.read()
+
+cl = Client()
+
+try:
+    cl.login(username, password)
+    comment = cl.media_comment(media_id, text)
+    cl.logout()
+    print(comment)
+except Exception as e:
+    print(f"Error: {e}")
+    exit(1)
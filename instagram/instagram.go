@@ -3,16 +3,42 @@ package instagram
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/btschwartz12/isza/caption"
 	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrTimeout is returned when the post script ran past the deadline of
+	// its context and was killed.
+	ErrTimeout = fmt.Errorf("post script timed out")
+	// ErrCancelled is returned when the context of the post script was
+	// cancelled and the script was killed.
+	ErrCancelled = fmt.Errorf("post script cancelled")
+)
+
+// killGrace is how long to wait for the output of a killed post script
+// before giving up on it.
+const killGrace = 5 * time.Second
+
+// mediaIDPrefix starts the line of the post script's output that gives the
+// ID of the published media.
+const mediaIDPrefix = "media_id="
+
+// ExecutePost runs the post script to publish post, and returns the
+// Instagram ID of the new media. If ctx is done first, the script's whole
+// process group is killed and the error wraps ErrTimeout or ErrCancelled;
+// the post may or may not have gone out.
 func ExecutePost(
 	ctx context.Context,
 	logger *zap.SugaredLogger,
@@ -21,13 +47,37 @@ func ExecutePost(
 	username string,
 	password string,
 	post *repo.Post,
-) error {
+) (mediaID string, err error) {
+	ctx, span := tracing.Start(ctx, "instagram.ExecutePost",
+		attribute.Int64("post.id", post.ID),
+		attribute.Int("post.photo_count", len(post.ImageFilenames)),
+		attribute.String("post.type", string(post.Type)),
+	)
+	defer func() { tracing.End(span, err) }()
 	logger.Infow("posting", "post", post.ID)
 
-	captionPath := filepath.Join(workingDir, "caption.txt")
-	err := os.WriteFile(captionPath, []byte(post.Caption), 0644)
+	if err := r.CheckAltText(ctx, post); err != nil {
+		return "", err
+	}
+
+	text, err := r.RenderCaption(ctx, username, post.ID, post.Caption, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("error rendering caption: %w", err)
+	}
+	lint := caption.Lint(text)
+	// Stories need no caption.
+	if text != "" || post.Type != repo.TypeStory {
+		if err := lint.Err(); err != nil {
+			return "", err
+		}
+	}
+
+	// Each post gets its own caption file, since different posts may be
+	// published at once.
+	captionPath := filepath.Join(workingDir, fmt.Sprintf("caption-%d.txt", post.ID))
+	err = os.WriteFile(captionPath, []byte(text), 0644)
 	if err != nil {
-		return fmt.Errorf("error writing caption file: %w", err)
+		return "", fmt.Errorf("error writing caption file: %w", err)
 	}
 	defer os.Remove(captionPath)
 
@@ -35,27 +85,95 @@ func ExecutePost(
 	for i, filename := range post.ImageFilenames {
 		fullpath, err := filepath.Abs(r.GetPathForPost(filename))
 		if err != nil {
-			return fmt.Errorf("error getting absolute path for post: %w", err)
+			return "", fmt.Errorf("error getting absolute path for post: %w", err)
 		}
 		fullPaths[i] = fullpath
 	}
 	pathsArg := strings.Join(fullPaths, ",")
+	var coverArg string
+	if post.CoverFilename != "" {
+		if coverArg, err = filepath.Abs(r.GetPathForPost(post.CoverFilename)); err != nil {
+			return "", fmt.Errorf("error getting absolute path for cover: %w", err)
+		}
+	}
+
+	metadataPath := filepath.Join(workingDir, fmt.Sprintf("metadata-%d.json", post.ID))
+	if err := writeMetadata(ctx, r, post, fullPaths, metadataPath); err != nil {
+		return "", err
+	}
+	defer os.Remove(metadataPath)
+
+	stdout, err := runScript(ctx, logger, workingDir, "post.py", username, password, pathsArg, captionPath, "false", string(post.Type), coverArg, metadataPath)
+	if err != nil {
+		return "", err
+	}
+	logger.Infow("post complete", "post", post.ID)
+
+	if err := r.RecordHashtagUsage(ctx, post.ID, lint.Hashtags); err != nil {
+		logger.Errorw("error recording hashtag usage", "post", post.ID, "error", err)
+	}
+	for _, line := range strings.Split(stdout, "\n") {
+		if id, ok := strings.CutPrefix(strings.TrimSpace(line), mediaIDPrefix); ok {
+			mediaID = id
+		}
+	}
+	return mediaID, nil
+}
+
+// ExecuteComment runs the comment script to comment text on the published
+// media of post. Like ExecutePost, the script is killed if ctx is done first.
+func ExecuteComment(
+	ctx context.Context,
+	logger *zap.SugaredLogger,
+	workingDir string,
+	username string,
+	password string,
+	post *repo.Post,
+	text string,
+) (err error) {
+	ctx, span := tracing.Start(ctx, "instagram.ExecuteComment", attribute.Int64("post.id", post.ID))
+	defer func() { tracing.End(span, err) }()
+	logger.Infow("commenting", "post", post.ID, "media_id", post.MediaID)
+
+	commentPath := filepath.Join(workingDir, fmt.Sprintf("comment-%d.txt", post.ID))
+	if err := os.WriteFile(commentPath, []byte(text), 0644); err != nil {
+		return fmt.Errorf("error writing comment file: %w", err)
+	}
+	defer os.Remove(commentPath)
+
+	if _, err := runScript(ctx, logger, workingDir, "comment.py", username, password, post.MediaID, commentPath); err != nil {
+		return err
+	}
+	logger.Infow("comment complete", "post", post.ID)
+	return nil
+}
 
+// runScript runs a Python script in workingDir and returns its output. If
+// ctx is done first, the script's whole process group is killed and the
+// error wraps ErrTimeout or ErrCancelled.
+func runScript(ctx context.Context, logger *zap.SugaredLogger, workingDir, script string, args ...string) (string, error) {
 	pythonPath := "python3"
-	scriptPath := filepath.Join(workingDir, "post.py")
+	scriptPath := filepath.Join(workingDir, script)
 
-	cmd := exec.Command(pythonPath, scriptPath, username, password, pathsArg, captionPath, "false")
+	cmd := exec.CommandContext(ctx, pythonPath, append([]string{scriptPath}, args...)...)
 	cmd.Dir = workingDir
+	cmd.WaitDelay = killGrace
+	setProcessGroup(cmd)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err = cmd.Run()
+	err := cmd.Run()
 	if err != nil {
-		logger.Errorw("error running post script", "err", err, "stdout", stdout.String(), "stderr", stderr.String())
-		return fmt.Errorf("error running post script: %w", err)
+		logger.Errorw("error running script", "script", script, "err", err, "stdout", stdout.String(), "stderr", stderr.String())
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return "", fmt.Errorf("%w: %w", ErrTimeout, err)
+		case errors.Is(ctx.Err(), context.Canceled):
+			return "", fmt.Errorf("%w: %w", ErrCancelled, err)
+		}
+		return "", fmt.Errorf("error running %s: %w", script, err)
 	}
-	logger.Infow("post complete", "post", post.ID)
-	return nil
+	return stdout.String(), nil
 }
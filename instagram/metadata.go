@@ -0,0 +1,64 @@
+package instagram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/btschwartz12/isza/repo"
+)
+
+// metadata is what the post script is told about a post besides its files
+// and caption.
+type metadata struct {
+	Images   []imageMetadata `json:"images"`
+	Location *location       `json:"location"`
+}
+
+type imageMetadata struct {
+	Path     string    `json:"path"`
+	AltText  string    `json:"alt_text"`
+	UserTags []userTag `json:"user_tags"`
+}
+
+type userTag struct {
+	Username string  `json:"username"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+}
+
+type location struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
+}
+
+// writeMetadata writes the alt text, user tags and location of post to path
+// as JSON. paths are the absolute paths of the post's files.
+func writeMetadata(ctx context.Context, r *repo.Repo, post *repo.Post, paths []string, path string) error {
+	images, err := r.GetPostImages(ctx, post.ID)
+	if err != nil {
+		return fmt.Errorf("error getting post images: %w", err)
+	}
+	m := metadata{Images: make([]imageMetadata, len(images))}
+	for i, image := range images {
+		tags := make([]userTag, len(image.UserTags))
+		for j, tag := range image.UserTags {
+			tags[j] = userTag{Username: tag.Username, X: tag.X, Y: tag.Y}
+		}
+		m.Images[i] = imageMetadata{Path: paths[i], AltText: image.AltText, UserTags: tags}
+	}
+	if l, ok := post.Location.Get(); ok {
+		m.Location = &location{Name: l.Name, Lat: l.Lat, Lng: l.Lng}
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("error marshalling metadata: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("error writing metadata file: %w", err)
+	}
+	return nil
+}
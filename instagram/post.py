@@ -1,21 +1,34 @@
+import json
 import sys
 from instagrapi import Client
+from instagrapi.types import Location, Usertag
 
 username = sys.argv[1]
 password = sys.argv[2]
 paths = sys.argv[3].split(',')
 caption_file = sys.argv[4]
 test = sys.argv[5] == 'true'
+post_type = sys.argv[6] if len(sys.argv) > 6 else ''
+cover = sys.argv[7] if len(sys.argv) > 7 and sys.argv[7] else None
+metadata_file = sys.argv[8] if len(sys.argv) > 8 else None
 
 with open(caption_file, 'r') as f:
     caption = f.read()
 
+metadata = {'images': [], 'location': None}
+if metadata_file:
+    with open(metadata_file, 'r') as f:
+        metadata = json.load(f)
+
 if test:
     print("Test mode: not posting to Instagram")
     print(f"Username: {username}")
     print(f"Password: {password}")
     print(f"Paths: {paths}")
+    print(f"Type: {post_type}")
+    print(f"Cover: {cover}")
     print(f"Caption: {caption}")
+    print(f"Metadata: {metadata}")
     exit(0)
 cl = Client()
 
@@ -24,14 +37,53 @@ if len(paths) == 0:
 
 attempts = 0
 
+def usertags(image):
+    return [
+        Usertag(user=cl.user_info_by_username(tag['username']), x=tag['x'], y=tag['y'])
+        for tag in image['user_tags']
+    ]
+
+def configure_album(images):
+    # Each child of an album is one of its files, in order, and carries that
+    # file's alt text and user tags.
+    def configure(childs, caption, _usertags=[], location=None, extra_data={}):
+        for child, image in zip(childs, images):
+            if image['alt_text']:
+                child['custom_accessibility_caption'] = image['alt_text']
+            tags = usertags(image)
+            if tags:
+                child['usertags'] = json.dumps({'in': [
+                    {'user_id': tag.user.pk, 'position': [tag.x, tag.y]} for tag in tags
+                ]})
+        return cl.album_configure(childs, caption, [], location, extra_data=extra_data)
+    return configure
+
 try:
     cl.login(username, password)
-    if len(paths) == 1:
-        media = cl.photo_upload(path=paths[0], caption=caption)
+    images = metadata['images']
+    location = Location(**metadata['location']) if metadata['location'] else None
+    # Stories are published without metadata.
+    extra = dict(location=location)
+    if len(images) == 1:
+        extra['usertags'] = usertags(images[0])
+        if images[0]['alt_text']:
+            extra['extra_data'] = {'custom_accessibility_caption': images[0]['alt_text']}
+    if post_type == 'story' and paths[0].lower().endswith(('.mp4', '.mov')):
+        media = cl.video_upload_to_story(path=paths[0], caption=caption)
+    elif post_type == 'story':
+        media = cl.photo_upload_to_story(path=paths[0], caption=caption)
+    elif post_type == 'reel':
+        media = cl.clip_upload(path=paths[0], caption=caption, thumbnail=cover, **extra)
+    elif len(paths) > 1:
+        media = cl.album_upload(paths, caption=caption, location=location, configure_handler=configure_album(images))
+    elif paths[0].lower().endswith(('.mp4', '.mov')):
+        media = cl.video_upload(path=paths[0], caption=caption, **extra)
     else:
-        media = cl.album_upload(paths, caption=caption)
+        media = cl.photo_upload(path=paths[0], caption=caption, **extra)
     cl.logout()
     print(media)
+    # The publisher reads this line to comment on the new media.
+    print(f"media_id={media.id}")
 except Exception as e:
     print(f"Error: {e}")
     exit(1)
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package instagram
+
+import "os/exec"
+
+// setProcessGroup leaves cmd as is; cancelling it kills only the script.
+func setProcessGroup(cmd *exec.Cmd) {}
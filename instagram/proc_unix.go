@@ -0,0 +1,18 @@
+//go:build unix
+
+package instagram
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs cmd in its own process group, so that a Ctrl-C meant
+// for isza does not kill a publish that isza is waiting on to drain, and so
+// that cancelling cmd kills the script along with anything it started.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,84 @@
+// Package logging carries a request-scoped logger through contexts and logs
+// HTTP requests.
+package logging
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the response header carrying the request ID, which is
+// taken from the request's own X-Request-Id header if it has one.
+const RequestIDHeader = "X-Request-Id"
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger in ctx, or fallback if there is none.
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// Route returns the chi route pattern that handled r, e.g.
+// "/api/posts/{id}", or "unmatched" if no route did. It is only complete once
+// the request has been routed.
+func Route(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return "unmatched"
+	}
+	// The server is mounted under "/*", which is all that is left of the
+	// pattern when no route matched.
+	pattern := rctx.RoutePattern()
+	if pattern == "" || pattern == "/*" {
+		return "unmatched"
+	}
+	return pattern
+}
+
+// Middleware gives each request an ID, puts a logger carrying it (and the
+// trace ID, if the request is traced) in the request context, and logs the
+// request once it is served. It must run after middleware.RequestID.
+func Middleware(logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			reqID := middleware.GetReqID(r.Context())
+			w.Header().Set(RequestIDHeader, reqID)
+
+			l := logger.With("request_id", reqID)
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				l = l.With("trace_id", sc.TraceID().String())
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(WithLogger(r.Context(), l)))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			l.Infow("request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", Route(r),
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration_ms", time.Since(start).Milliseconds(),
+				"remote_addr", r.RemoteAddr,
+			)
+		})
+	}
+}
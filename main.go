@@ -1,24 +1,53 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	flags "github.com/jessevdk/go-flags"
 	"go.uber.org/zap"
 
+	"github.com/btschwartz12/isza/notify"
 	"github.com/btschwartz12/isza/server"
+	"github.com/btschwartz12/isza/tracing"
 )
 
 type arguments struct {
-	Port            int    `short:"p" long:"port" description:"Port to listen on" default:"8000"`
-	VarDir          string `short:"v" long:"var-dir" env:"ISZA_VAR_DIR" description:"Directory to store data"`
-	DevLogging      bool   `short:"d" long:"dev-logging" description:"Enable development logging"`
-	AuthToken       string `short:"t" long:"auth-token" env:"ISZA_AUTH_TOKEN" description:"Authorization token"`
-	InstaUsername   string `short:"u" long:"insta-username" env:"ISZA_INSTA_USERNAME" description:"Instagram username"`
-	InstaPassword   string `short:"w" long:"insta-password" env:"ISZA_INSTA_PASSWORD" description:"Instagram password"`
-	InstaWorkingDir string `short:"i" long:"insta-working-dir" env:"ISZA_INSTA_WORKING_DIR" description:"Instagram working directory"`
+	Port              int           `short:"p" long:"port" description:"Port to listen on" default:"8000"`
+	VarDir            string        `short:"v" long:"var-dir" env:"ISZA_VAR_DIR" description:"Directory to store data"`
+	DevLogging        bool          `short:"d" long:"dev-logging" description:"Enable development logging"`
+	AuthToken         string        `short:"t" long:"auth-token" env:"ISZA_AUTH_TOKEN" description:"Authorization token"`
+	InstaUsername     string        `short:"u" long:"insta-username" env:"ISZA_INSTA_USERNAME" description:"Instagram username"`
+	InstaPassword     string        `short:"w" long:"insta-password" env:"ISZA_INSTA_PASSWORD" description:"Instagram password"`
+	InstaWorkingDir   string        `short:"i" long:"insta-working-dir" env:"ISZA_INSTA_WORKING_DIR" description:"Instagram working directory"`
+	PostTimes         string        `long:"post-times" env:"ISZA_POST_TIMES" default:"12:00,18:00" description:"Comma separated daily post times (24-hour, in the instance time zone)"`
+	StoryTimes        string        `long:"story-times" env:"ISZA_STORY_TIMES" default:"09:00" description:"Comma separated daily story times (24-hour, in the instance time zone)"`
+	TimeZone          string        `long:"time-zone" env:"ISZA_TIME_ZONE" default:"America/New_York" description:"IANA time zone used for scheduling"`
+	QueueLow          string        `long:"queue-low" env:"ISZA_QUEUE_LOW" default:"2d" description:"Alert when the queue falls below this many posts (e.g. 5) or days of coverage (e.g. 2d); 0 disables"`
+	SMTPHost          string        `long:"smtp-host" env:"ISZA_SMTP_HOST" description:"SMTP server for email notifications; empty disables them"`
+	SMTPPort          int           `long:"smtp-port" env:"ISZA_SMTP_PORT" default:"587" description:"SMTP server port"`
+	SMTPUsername      string        `long:"smtp-username" env:"ISZA_SMTP_USERNAME" description:"SMTP username; empty skips authentication"`
+	SMTPPassword      string        `long:"smtp-password" env:"ISZA_SMTP_PASSWORD" description:"SMTP password"`
+	SMTPTLS           string        `long:"smtp-tls" env:"ISZA_SMTP_TLS" default:"starttls" choice:"none" choice:"starttls" choice:"tls" description:"How to secure the SMTP connection"`
+	SMTPFrom          string        `long:"smtp-from" env:"ISZA_SMTP_FROM" description:"Sender address of notifications"`
+	SMTPTo            string        `long:"smtp-to" env:"ISZA_SMTP_TO" description:"Comma separated recipients of notifications"`
+	PublishTimeout    time.Duration `long:"publish-timeout" env:"ISZA_PUBLISH_TIMEOUT" default:"5m" description:"How long the post script may run before it is killed and the post marked as interrupted"`
+	DrainTimeout      time.Duration `long:"drain-timeout" env:"ISZA_DRAIN_TIMEOUT" default:"60s" description:"How long to wait on shutdown for requests and publishes in progress; the container stop timeout should be longer"`
+	MaxCarouselItems  int           `long:"max-carousel-items" env:"ISZA_MAX_CAROUSEL_ITEMS" default:"20" description:"Most files a carousel may have, at most Instagram's limit of 20"`
+	MaxUploadMb       int           `long:"max-upload-mb" env:"ISZA_MAX_UPLOAD_MB" default:"2048" description:"Most a single request may upload, in megabytes; raise it with --max-carousel-items when carousels hold long videos"`
+	RequireAltText    bool          `long:"require-alt-text" env:"ISZA_REQUIRE_ALT_TEXT" description:"Hold back photos that have no alt text: their posts cannot be queued and fail to publish"`
+	IdempotencyWindow time.Duration `long:"idempotency-window" env:"ISZA_IDEMPOTENCY_WINDOW" default:"24h" description:"How long the response to a request with an Idempotency-Key header is kept for replay"`
+	DailySummary      string        `long:"daily-summary" env:"ISZA_DAILY_SUMMARY" default:"20:00" description:"Time of day (in the instance time zone) to email the daily summary; empty disables it"`
+	CalendarToken     string        `long:"calendar-token" env:"ISZA_CALENDAR_TOKEN" description:"Read-only token calendar apps pass to /api/calendar.ics; empty disables the feed"`
+	MetricsToken      string        `long:"metrics-token" env:"ISZA_METRICS_TOKEN" description:"Token required to read /metrics; empty requires the API token"`
+	OTLPEndpoint      string        `long:"otlp-endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" description:"OTLP/HTTP collector to export traces to, e.g. http://localhost:4318; empty disables tracing"`
 }
 
 var args arguments
@@ -59,8 +88,45 @@ func main() {
 	}
 	logger := l.Sugar()
 
+	smtp := notify.Config{
+		Host:         args.SMTPHost,
+		Port:         args.SMTPPort,
+		Username:     args.SMTPUsername,
+		Password:     args.SMTPPassword,
+		TLS:          notify.TLSMode(args.SMTPTLS),
+		From:         args.SMTPFrom,
+		DailySummary: args.DailySummary,
+	}
+	for _, to := range strings.Split(args.SMTPTo, ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			smtp.To = append(smtp.To, to)
+		}
+	}
+
+	if err := tracing.Init(context.Background(), logger, args.OTLPEndpoint, "isza"); err != nil {
+		logger.Fatalw("Error initializing tracing", "error", err)
+	}
+
 	s := &server.Server{}
-	err = s.Init(logger, args.VarDir, args.AuthToken, args.InstaUsername, args.InstaPassword, args.InstaWorkingDir)
+	err = s.Init(logger, server.Config{
+		VarDir:            args.VarDir,
+		AuthToken:         args.AuthToken,
+		InstaUsername:     args.InstaUsername,
+		InstaPassword:     args.InstaPassword,
+		InstaWorkingDir:   args.InstaWorkingDir,
+		PostTimes:         args.PostTimes,
+		StoryTimes:        args.StoryTimes,
+		TimeZone:          args.TimeZone,
+		QueueLow:          args.QueueLow,
+		SMTP:              smtp,
+		MetricsToken:      args.MetricsToken,
+		CalendarToken:     args.CalendarToken,
+		MaxCarouselItems:  args.MaxCarouselItems,
+		MaxUploadMb:       args.MaxUploadMb,
+		RequireAltText:    args.RequireAltText,
+		PublishTimeout:    args.PublishTimeout,
+		IdempotencyWindow: args.IdempotencyWindow,
+	})
 	if err != nil {
 		logger.Fatalw("Error initializing server", "error", err)
 	}
@@ -68,11 +134,46 @@ func main() {
 	r := chi.NewRouter()
 	r.Mount("/", s.Router())
 
-	errChan := make(chan error)
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", args.Port),
+		Handler: r,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errChan := make(chan error, 1)
 	go func() {
 		logger.Infow("Starting server", "port", args.Port)
-		errChan <- http.ListenAndServe(fmt.Sprintf(":%d", args.Port), r)
+		errChan <- srv.ListenAndServe()
 	}()
-	err = <-errChan
-	logger.Fatalw("http server failed", "error", err)
+	select {
+	case err = <-errChan:
+		logger.Fatalw("http server failed", "error", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	logger.Infow("Shutting down", "drain_timeout", args.DrainTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), args.DrainTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.Shutdown(drainCtx); err != nil {
+			logger.Errorw("error shutting down http server", "error", err)
+		}
+	}()
+	if err := s.Shutdown(drainCtx); err != nil {
+		logger.Errorw("error draining publishes", "error", err)
+	}
+	wg.Wait()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := tracing.Shutdown(flushCtx); err != nil {
+		logger.Errorw("error flushing traces", "error", err)
+	}
+	logger.Infow("Shut down")
+	logger.Sync()
 }
@@ -0,0 +1,37 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+var ErrInvalidImage = fmt.Errorf("invalid image")
+
+// Image describes a JPEG, PNG or GIF image.
+type Image struct {
+	Format string
+	Width  int
+	Height int
+}
+
+// AspectRatio returns width over height.
+func (i *Image) AspectRatio() float64 {
+	if i.Height == 0 {
+		return 0
+	}
+	return float64(i.Width) / float64(i.Height)
+}
+
+// ProbeImage reads the header of the image in r. It returns an error wrapping
+// ErrInvalidImage if r is not a JPEG, PNG or GIF image.
+func ProbeImage(r io.Reader) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+	return &Image{Format: format, Width: cfg.Width, Height: cfg.Height}, nil
+}
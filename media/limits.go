@@ -0,0 +1,101 @@
+package media
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limits are what Instagram accepts for a kind of video. The minimum width and
+// the aspect ratio also apply to the photos of stories.
+type Limits struct {
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	MinWidth    int
+	MaxWidth    int
+	// MinAspect and MaxAspect bound width over height.
+	MinAspect float64
+	MaxAspect float64
+}
+
+var (
+	// ReelLimits apply to reels.
+	ReelLimits = Limits{
+		MinDuration: 3 * time.Second,
+		MaxDuration: 15 * time.Minute,
+		MinWidth:    320,
+		MaxWidth:    1920,
+		MinAspect:   9.0 / 16.0,
+		MaxAspect:   16.0 / 9.0,
+	}
+	// FeedLimits apply to single feed videos and to videos in carousels.
+	FeedLimits = Limits{
+		MinDuration: 3 * time.Second,
+		MaxDuration: 60 * time.Second,
+		MinWidth:    320,
+		MaxWidth:    1920,
+		MinAspect:   4.0 / 5.0,
+		MaxAspect:   16.0 / 9.0,
+	}
+	// StoryLimits apply to stories, photos as well as videos.
+	StoryLimits = Limits{
+		MinDuration: 1 * time.Second,
+		MaxDuration: 60 * time.Second,
+		MinWidth:    320,
+		MaxWidth:    1920,
+		MinAspect:   9.0 / 16.0,
+		MaxAspect:   9.0 / 16.0,
+	}
+
+	videoCodecs = map[string]bool{"avc1": true, "avc3": true, "hvc1": true, "hev1": true}
+	audioCodecs = map[string]bool{"mp4a": true}
+)
+
+// aspectTolerance allows for encoders that round dimensions, e.g. 1080x1918
+// for 9:16.
+const aspectTolerance = 0.01
+
+// Check returns an error wrapping ErrInvalidVideo describing the first way v
+// falls outside l, or the codecs Instagram accepts.
+func (l Limits) Check(v *Video) error {
+	if !videoCodecs[v.VideoCodec] {
+		return fmt.Errorf("%w: video codec %q is not H.264 or HEVC", ErrInvalidVideo, v.VideoCodec)
+	}
+	if v.AudioCodec != "" && !audioCodecs[v.AudioCodec] {
+		return fmt.Errorf("%w: audio codec %q is not AAC", ErrInvalidVideo, v.AudioCodec)
+	}
+	if v.Duration < l.MinDuration || v.Duration > l.MaxDuration {
+		return fmt.Errorf("%w: duration %s is not between %s and %s", ErrInvalidVideo, v.Duration.Round(100*time.Millisecond), l.MinDuration, l.MaxDuration)
+	}
+	if v.Width < l.MinWidth || v.Width > l.MaxWidth {
+		return fmt.Errorf("%w: width %dpx is not between %dpx and %dpx", ErrInvalidVideo, v.Width, l.MinWidth, l.MaxWidth)
+	}
+	if !l.aspectOK(v.AspectRatio()) {
+		return fmt.Errorf("%w: aspect ratio %dx%d is not %s", ErrInvalidVideo, v.Width, v.Height, l.aspects())
+	}
+	return nil
+}
+
+// CheckImage returns an error wrapping ErrInvalidImage describing the first
+// way the size of i falls outside l. There is no maximum width, since
+// Instagram scales large photos down.
+func (l Limits) CheckImage(i *Image) error {
+	if i.Width < l.MinWidth {
+		return fmt.Errorf("%w: width %dpx is less than %dpx", ErrInvalidImage, i.Width, l.MinWidth)
+	}
+	if !l.aspectOK(i.AspectRatio()) {
+		return fmt.Errorf("%w: aspect ratio %dx%d is not %s", ErrInvalidImage, i.Width, i.Height, l.aspects())
+	}
+	return nil
+}
+
+func (l Limits) aspectOK(aspect float64) bool {
+	return aspect >= l.MinAspect-aspectTolerance && aspect <= l.MaxAspect+aspectTolerance
+}
+
+// aspects describes the aspect ratios l allows.
+func (l Limits) aspects() string {
+	if l.MinAspect == l.MaxAspect {
+		return fmt.Sprintf("%.2f", l.MinAspect)
+	}
+	return fmt.Sprintf("between %.2f and %.2f", l.MinAspect, l.MaxAspect)
+}
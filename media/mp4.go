@@ -0,0 +1,272 @@
+// Package media inspects uploaded videos. It reads the boxes of MP4 and
+// QuickTime files, which share the ISO base media file format, to find the
+// container, duration, dimensions and codecs without decoding any frames.
+package media
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+var ErrInvalidVideo = fmt.Errorf("invalid video")
+
+// Containers recognised by ProbeVideo.
+const (
+	ContainerMP4 = "mp4"
+	ContainerMOV = "mov"
+)
+
+// Video describes an MP4 or QuickTime video.
+type Video struct {
+	Container string
+	Duration  time.Duration
+	// Width and Height are as displayed, after any rotation.
+	Width  int
+	Height int
+	// VideoCodec and AudioCodec are the sample entry types of the first
+	// track of each kind, e.g. "avc1" and "mp4a". AudioCodec is empty for a
+	// silent video.
+	VideoCodec string
+	AudioCodec string
+}
+
+// AspectRatio returns width over height.
+func (v *Video) AspectRatio() float64 {
+	if v.Height == 0 {
+		return 0
+	}
+	return float64(v.Width) / float64(v.Height)
+}
+
+// box is the location of a box's payload within the file.
+type box struct {
+	typ        string
+	start, end int64
+}
+
+// ProbeVideo reads the video in r, which is size bytes long. It returns an
+// error wrapping ErrInvalidVideo if r is not an MP4 or QuickTime file with a
+// video track.
+func ProbeVideo(r io.ReaderAt, size int64) (*Video, error) {
+	if header, err := readAt(r, 4, 4); err != nil || string(header) != "ftyp" {
+		return nil, fmt.Errorf("%w: not an mp4 or quicktime file", ErrInvalidVideo)
+	}
+	top, err := readBoxes(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	v := &Video{Container: ContainerMP4}
+	brand, err := readAt(r, top[0].start, 4)
+	if err != nil {
+		return nil, err
+	}
+	if string(brand) == "qt  " {
+		v.Container = ContainerMOV
+	}
+
+	moov, ok := find(top, "moov")
+	if !ok {
+		return nil, fmt.Errorf("%w: no movie header", ErrInvalidVideo)
+	}
+	children, err := readBoxes(r, moov.start, moov.end)
+	if err != nil {
+		return nil, err
+	}
+	mvhd, ok := find(children, "mvhd")
+	if !ok {
+		return nil, fmt.Errorf("%w: no movie header", ErrInvalidVideo)
+	}
+	if v.Duration, err = readDuration(r, mvhd); err != nil {
+		return nil, err
+	}
+
+	for _, trak := range children {
+		if trak.typ != "trak" {
+			continue
+		}
+		t, err := readTrack(r, trak)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.handler == "vide" && v.VideoCodec == "":
+			v.VideoCodec = t.codec
+			v.Width, v.Height = t.width, t.height
+		case t.handler == "soun" && v.AudioCodec == "":
+			v.AudioCodec = t.codec
+		}
+	}
+	if v.VideoCodec == "" {
+		return nil, fmt.Errorf("%w: no video track", ErrInvalidVideo)
+	}
+	return v, nil
+}
+
+// readBoxes returns the boxes between start and end.
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	for off := start; off+8 <= end; {
+		header, err := readAt(r, off, 8)
+		if err != nil {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		typ := string(header[4:8])
+		headerLen := int64(8)
+		switch size {
+		case 0:
+			// The box runs to the end of the file.
+			size = end - off
+		case 1:
+			large, err := readAt(r, off+8, 8)
+			if err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(large))
+			headerLen = 16
+		}
+		// Compared without adding to off, so that a huge 64-bit size cannot
+		// overflow past the check.
+		if size < headerLen || size > end-off {
+			return nil, fmt.Errorf("%w: %q box overruns its container", ErrInvalidVideo, typ)
+		}
+		boxes = append(boxes, box{typ: typ, start: off + headerLen, end: off + size})
+		off += size
+	}
+	return boxes, nil
+}
+
+func find(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// findPath descends through nested boxes, e.g. "mdia", "minf", "stbl".
+func findPath(r io.ReaderAt, parent box, path ...string) (box, bool, error) {
+	for _, typ := range path {
+		children, err := readBoxes(r, parent.start, parent.end)
+		if err != nil {
+			return box{}, false, err
+		}
+		var ok bool
+		if parent, ok = find(children, typ); !ok {
+			return box{}, false, nil
+		}
+	}
+	return parent, true, nil
+}
+
+func readAt(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: truncated file", ErrInvalidVideo)
+		}
+		return nil, fmt.Errorf("error reading video: %w", err)
+	}
+	return buf, nil
+}
+
+// readDuration reads the duration from a movie header box.
+func readDuration(r io.ReaderAt, mvhd box) (time.Duration, error) {
+	version, err := readAt(r, mvhd.start, 1)
+	if err != nil {
+		return 0, err
+	}
+	var timescale, duration uint64
+	if version[0] == 1 {
+		b, err := readAt(r, mvhd.start+20, 12)
+		if err != nil {
+			return 0, err
+		}
+		timescale = uint64(binary.BigEndian.Uint32(b[:4]))
+		duration = binary.BigEndian.Uint64(b[4:])
+	} else {
+		b, err := readAt(r, mvhd.start+12, 8)
+		if err != nil {
+			return 0, err
+		}
+		timescale = uint64(binary.BigEndian.Uint32(b[:4]))
+		duration = uint64(binary.BigEndian.Uint32(b[4:]))
+	}
+	if timescale == 0 {
+		return 0, fmt.Errorf("%w: zero timescale", ErrInvalidVideo)
+	}
+	seconds := float64(duration) / float64(timescale)
+	if seconds > math.MaxInt64/float64(time.Second) {
+		return 0, fmt.Errorf("%w: duration out of range", ErrInvalidVideo)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+type track struct {
+	handler       string
+	codec         string
+	width, height int
+}
+
+func readTrack(r io.ReaderAt, trak box) (track, error) {
+	var t track
+	if hdlr, ok, err := findPath(r, trak, "mdia", "hdlr"); err != nil {
+		return t, err
+	} else if ok {
+		b, err := readAt(r, hdlr.start+8, 4)
+		if err != nil {
+			return t, err
+		}
+		t.handler = string(b)
+	}
+	if stsd, ok, err := findPath(r, trak, "mdia", "minf", "stbl", "stsd"); err != nil {
+		return t, err
+	} else if ok && stsd.end-stsd.start >= 16 {
+		// Version, flags and entry count, then the first entry's header.
+		b, err := readAt(r, stsd.start+12, 4)
+		if err != nil {
+			return t, err
+		}
+		t.codec = string(b)
+	}
+	if tkhd, ok, err := findPath(r, trak, "tkhd"); err != nil {
+		return t, err
+	} else if ok {
+		if t.width, t.height, err = readDimensions(r, tkhd); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+// readDimensions reads the display size from a track header box, swapping
+// width and height if its matrix rotates the track by a quarter turn.
+func readDimensions(r io.ReaderAt, tkhd box) (int, int, error) {
+	version, err := readAt(r, tkhd.start, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Skip the version, flags, times, track ID and duration, then the
+	// reserved fields, layer, group and volume.
+	off := tkhd.start + 4 + 20 + 16
+	if version[0] == 1 {
+		off = tkhd.start + 4 + 32 + 16
+	}
+	b, err := readAt(r, off, 36+8)
+	if err != nil {
+		return 0, 0, err
+	}
+	a := int32(binary.BigEndian.Uint32(b[0:4]))
+	c := int32(binary.BigEndian.Uint32(b[4:8]))
+	// Width and height are 16.16 fixed point.
+	width := int(binary.BigEndian.Uint32(b[36:40]) >> 16)
+	height := int(binary.BigEndian.Uint32(b[40:44]) >> 16)
+	if a == 0 && (c == 1<<16 || c == -1<<16) {
+		width, height = height, width
+	}
+	return width, height, nil
+}
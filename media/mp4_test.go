@@ -0,0 +1,193 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(b, typ...), body...)
+}
+
+// fullBox is a box with a version and flags before its payload.
+func fullBox(typ string, version byte, payload ...[]byte) []byte {
+	return mp4Box(typ, append([][]byte{{version, 0, 0, 0}}, payload...)...)
+}
+
+func u32(vs ...uint32) []byte {
+	var b []byte
+	for _, v := range vs {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+func u64(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+var (
+	identity    = []int32{1 << 16, 0, 0, 0, 1 << 16, 0, 0, 0, 1 << 30}
+	quarterTurn = []int32{0, 1 << 16, 0, -1 << 16, 0, 0, 0, 0, 1 << 30}
+)
+
+type testTrack struct {
+	handler, codec string
+	width, height  uint32
+	matrix         []int32
+	// version of the track header; version 1 has 64-bit times.
+	version byte
+}
+
+func (t testTrack) box() []byte {
+	matrix := t.matrix
+	if matrix == nil {
+		matrix = identity
+	}
+	var m []byte
+	for _, v := range matrix {
+		m = binary.BigEndian.AppendUint32(m, uint32(v))
+	}
+	times := u32(0, 0, 1, 0, 0) // created, modified, track ID, reserved, duration
+	if t.version == 1 {
+		times = bytes.Join([][]byte{u64(0), u64(0), u32(1, 0), u64(0)}, nil)
+	}
+	tkhd := fullBox("tkhd", t.version, times, make([]byte, 16), m, u32(t.width<<16, t.height<<16))
+	hdlr := fullBox("hdlr", 0, u32(0), []byte(t.handler), make([]byte, 12), []byte("x\x00"))
+	stsd := fullBox("stsd", 0, u32(1), mp4Box(t.codec, make([]byte, 70)))
+	return mp4Box("trak", tkhd, mp4Box("mdia", hdlr, mp4Box("minf", mp4Box("stbl", stsd))))
+}
+
+func mvhd(timescale, duration uint32) []byte {
+	return fullBox("mvhd", 0, u32(0, 0, timescale, duration), make([]byte, 80))
+}
+
+func ftyp(brand string) []byte {
+	return mp4Box("ftyp", []byte(brand), u32(0), []byte("isommp42"))
+}
+
+func video(w, h uint32) testTrack {
+	return testTrack{handler: "vide", codec: "avc1", width: w, height: h}
+}
+
+var audio = testTrack{handler: "soun", codec: "mp4a"}
+
+// movie builds a file with the given movie header and tracks.
+func movie(brand string, header []byte, tracks ...testTrack) []byte {
+	moov := [][]byte{header}
+	for _, t := range tracks {
+		moov = append(moov, t.box())
+	}
+	return bytes.Join([][]byte{ftyp(brand), mp4Box("moov", moov...), mp4Box("mdat", make([]byte, 64))}, nil)
+}
+
+func TestProbeVideo(t *testing.T) {
+	reel := movie("isom", mvhd(1000, 30500), video(1080, 1920), audio)
+	rotated := video(1920, 1080)
+	rotated.matrix = quarterTurn
+	v1 := video(720, 1280)
+	v1.version = 1
+	mvhdV1 := fullBox("mvhd", 1, u64(0), u64(0), u32(600), u64(600*90), make([]byte, 80))
+
+	// A box declaring a 64-bit size, and one running to the end of the file.
+	square := movie("isom", mvhd(1000, 5000), video(1080, 1080))
+	largeSize := bytes.Join([][]byte{square, u32(1), []byte("free"), u64(16 + 8), make([]byte, 8)}, nil)
+	toEnd := bytes.Join([][]byte{square, u32(0), []byte("free"), make([]byte, 32)}, nil)
+
+	oversized := bytes.Clone(reel)
+	moovAt := bytes.Index(oversized, []byte("moov")) - 4
+	binary.BigEndian.PutUint32(oversized[moovAt:], uint32(len(oversized)))
+	undersized := bytes.Clone(reel)
+	binary.BigEndian.PutUint32(undersized[moovAt:], 4)
+	hugeLarge := bytes.Join([][]byte{reel, u32(1), []byte("free"), u64(math.MaxInt64)}, nil)
+	negativeLarge := bytes.Join([][]byte{reel, u32(1), []byte("free"), u64(math.MaxUint64)}, nil)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		size    int64 // defaults to len(data)
+		want    *Video
+		wantErr bool
+	}{
+		{
+			name: "reel",
+			data: reel,
+			want: &Video{Container: ContainerMP4, Duration: 30500 * time.Millisecond, Width: 1080, Height: 1920, VideoCodec: "avc1", AudioCodec: "mp4a"},
+		},
+		{
+			name: "quicktime",
+			data: movie("qt  ", mvhd(600, 1800), video(1080, 1350)),
+			want: &Video{Container: ContainerMOV, Duration: 3 * time.Second, Width: 1080, Height: 1350, VideoCodec: "avc1"},
+		},
+		{
+			name: "rotated",
+			data: movie("isom", mvhd(1000, 10000), rotated, audio),
+			want: &Video{Container: ContainerMP4, Duration: 10 * time.Second, Width: 1080, Height: 1920, VideoCodec: "avc1", AudioCodec: "mp4a"},
+		},
+		{
+			name: "version 1 headers",
+			data: movie("isom", mvhdV1, v1),
+			want: &Video{Container: ContainerMP4, Duration: 90 * time.Second, Width: 720, Height: 1280, VideoCodec: "avc1"},
+		},
+		{
+			name: "audio track first",
+			data: movie("isom", mvhd(1000, 5000), audio, testTrack{handler: "vide", codec: "hvc1", width: 1080, height: 1080}),
+			want: &Video{Container: ContainerMP4, Duration: 5 * time.Second, Width: 1080, Height: 1080, VideoCodec: "hvc1", AudioCodec: "mp4a"},
+		},
+		{
+			name: "64-bit box size",
+			data: largeSize,
+			want: &Video{Container: ContainerMP4, Duration: 5 * time.Second, Width: 1080, Height: 1080, VideoCodec: "avc1"},
+		},
+		{
+			name: "box running to the end",
+			data: toEnd,
+			want: &Video{Container: ContainerMP4, Duration: 5 * time.Second, Width: 1080, Height: 1080, VideoCodec: "avc1"},
+		},
+		{name: "empty", data: nil, wantErr: true},
+		{name: "not mp4", data: []byte("\x89PNG\r\n\x1a\n0000000000000000"), wantErr: true},
+		{name: "truncated in the movie box", data: reel[:len(reel)/2], wantErr: true},
+		{name: "truncated in the header", data: reel[:6], wantErr: true},
+		{name: "shorter than its size", data: reel[:len(reel)/2], size: int64(len(reel)), wantErr: true},
+		{name: "oversized box", data: oversized, wantErr: true},
+		{name: "box smaller than its header", data: undersized, wantErr: true},
+		{name: "oversized 64-bit box", data: hugeLarge, wantErr: true},
+		{name: "64-bit size past int64", data: negativeLarge, wantErr: true},
+		{name: "no movie box", data: ftyp("isom"), wantErr: true},
+		{name: "no movie header", data: bytes.Join([][]byte{ftyp("isom"), mp4Box("moov", video(1080, 1080).box())}, nil), wantErr: true},
+		{name: "no video track", data: movie("isom", mvhd(1000, 5000), audio), wantErr: true},
+		{name: "zero timescale", data: movie("isom", mvhd(0, 5000), video(1080, 1080)), wantErr: true},
+		{
+			name:    "duration out of range",
+			data:    movie("isom", fullBox("mvhd", 1, u64(0), u64(0), u32(1), u64(math.MaxUint64), make([]byte, 80)), video(1080, 1080)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := tt.size
+			if size == 0 {
+				size = int64(len(tt.data))
+			}
+			got, err := ProbeVideo(bytes.NewReader(tt.data), size)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidVideo) {
+					t.Fatalf("ProbeVideo() = %+v, %v, want an error wrapping ErrInvalidVideo", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProbeVideo(): %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("ProbeVideo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
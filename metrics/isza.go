@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/repo"
+)
+
+// storageRefresh is how long a measured storage size is reused for, since
+// measuring it walks every file in the var dir.
+const storageRefresh = time.Minute
+
+var (
+	PublishAttempts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "isza_publish_attempts_total",
+		Help: "Number of attempts to publish a post.",
+	})
+	PublishSuccesses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "isza_publish_successes_total",
+		Help: "Number of posts published successfully.",
+	})
+	PublishFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "isza_publish_failures_total",
+		Help: "Number of attempts to publish a post that failed.",
+	})
+	PublishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "isza_publish_duration_seconds",
+		Help:    "Time taken to run the post script, by result.",
+		Buckets: []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"result"})
+
+	postsDesc = prometheus.NewDesc(
+		"isza_posts",
+		"Number of posts, by status.",
+		[]string{"status"}, nil,
+	)
+	storageDesc = prometheus.NewDesc(
+		"isza_storage_bytes",
+		"Bytes used by the database and uploaded images.",
+		nil, nil,
+	)
+	lastPublishDesc = prometheus.NewDesc(
+		"isza_last_publish_success_timestamp_seconds",
+		"Unix time of the last successful publish, or 0 if there has been none.",
+		nil, nil,
+	)
+)
+
+// repoCollector reports the posts and storage of the repo at scrape time.
+type repoCollector struct {
+	logger *zap.SugaredLogger
+	rpo    *repo.Repo
+
+	mu         sync.Mutex
+	storage    int64
+	measuredAt time.Time
+}
+
+func newRepoCollector(logger *zap.SugaredLogger, rpo *repo.Repo) *repoCollector {
+	return &repoCollector{logger: logger, rpo: rpo}
+}
+
+func (c *repoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- postsDesc
+	ch <- storageDesc
+	ch <- lastPublishDesc
+}
+
+func (c *repoCollector) Collect(ch chan<- prometheus.Metric) {
+	posts, err := c.rpo.GetAllPosts(context.Background())
+	if err != nil {
+		c.logger.Errorw("error getting posts for metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(postsDesc, err)
+	} else {
+		counts := make(map[repo.PostStatus]int)
+		var lastPublished time.Time
+		for _, post := range posts {
+			counts[post.Status]++
+			if postedAt, ok := post.PostedAt.Get(); ok && post.Status == repo.StatusPosted && postedAt.After(lastPublished) {
+				lastPublished = postedAt
+			}
+		}
+		for _, status := range repo.AllStatuses {
+			ch <- prometheus.MustNewConstMetric(postsDesc, prometheus.GaugeValue, float64(counts[status]), string(status))
+		}
+		var last float64
+		if !lastPublished.IsZero() {
+			last = float64(lastPublished.Unix())
+		}
+		ch <- prometheus.MustNewConstMetric(lastPublishDesc, prometheus.GaugeValue, last)
+	}
+
+	storage, err := c.storageBytes()
+	if err != nil {
+		c.logger.Errorw("error getting storage size for metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(storageDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(storageDesc, prometheus.GaugeValue, float64(storage))
+}
+
+// storageBytes returns the storage size measured within storageRefresh, or
+// measures it again.
+func (c *repoCollector) storageBytes() (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.measuredAt) < storageRefresh {
+		return c.storage, nil
+	}
+	storage, err := c.rpo.StorageBytes()
+	if err != nil {
+		return 0, err
+	}
+	c.storage, c.measuredAt = storage, time.Now()
+	return storage, nil
+}
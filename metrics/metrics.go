@@ -0,0 +1,84 @@
+// Package metrics serves isza's Prometheus metrics, along with those of the
+// Go runtime and the process.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/logging"
+	"github.com/btschwartz12/isza/repo"
+)
+
+// registry holds the collectors that do not depend on the repo.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "isza_http_requests_total",
+		Help: "Number of HTTP requests, by method, route and status code.",
+	}, []string{"method", "route", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "isza_http_request_duration_seconds",
+		Help:    "HTTP request latencies, by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		PublishAttempts,
+		PublishSuccesses,
+		PublishFailures,
+		PublishDuration,
+	)
+}
+
+// Middleware records the count and latency of requests by chi route pattern,
+// so that e.g. every post ID is counted under /api/posts/{id}. Requests that
+// match no route are counted under "unmatched".
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := logging.Route(r)
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handler serves the metrics to requests that carry token in the
+// Authorization header, optionally as a bearer token.
+func Handler(logger *zap.SugaredLogger, rpo *repo.Repo, token string) http.Handler {
+	repoRegistry := prometheus.NewRegistry()
+	repoRegistry.MustRegister(newRepoCollector(logger, rpo))
+	metrics := promhttp.HandlerFor(prometheus.Gatherers{registry, repoRegistry}, promhttp.HandlerOpts{})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
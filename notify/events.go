@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
+)
+
+// Templates of the notifications, each rendered from <name>.txt.tmpl and
+// <name>.html.tmpl.
+const (
+	templatePublishFailed = "publish_failed"
+	templateQueueLow      = "queue_low"
+	templateDailySummary  = "daily_summary"
+	templateTest          = "test"
+)
+
+type publishFailedData struct {
+	Post  *repo.Post
+	Error string
+}
+
+// PublishFailed notifies that post could not be published.
+func (n *Notifier) PublishFailed(ctx context.Context, post *repo.Post, cause error) {
+	n.Notify(ctx, templatePublishFailed, fmt.Sprintf("Post %d failed to publish", post.ID), publishFailedData{
+		Post:  post,
+		Error: cause.Error(),
+	})
+}
+
+type queueLowData struct {
+	Queued       int
+	CoveredUntil time.Time
+	Threshold    string
+}
+
+// QueueLow notifies that the queue has fallen below the running-low
+// threshold.
+func (n *Notifier) QueueLow(ctx context.Context, queued int, coveredUntil time.Time, threshold string) {
+	n.Notify(ctx, templateQueueLow, fmt.Sprintf("Queue is running low: %d posts left", queued), queueLowData{
+		Queued:       queued,
+		CoveredUntil: coveredUntil,
+		Threshold:    threshold,
+	})
+}
+
+// SendTest sends a test message, to check the SMTP settings.
+func (n *Notifier) SendTest(ctx context.Context) error {
+	return n.Send(ctx, templateTest, "isza test notification", nil)
+}
+
+type dailySummaryData struct {
+	Since     time.Time
+	Published []repo.Post
+	Failed    []repo.Post
+	Queued    int
+	Upcoming  []schedule.Projection
+}
+
+// summaryWindow is how far back the daily summary reports on and how far
+// ahead it looks.
+const summaryWindow = 24 * time.Hour
+
+// SendDailySummary emails what was published in the last day, what is
+// failing, and what goes out in the next day.
+func (n *Notifier) SendDailySummary(ctx context.Context, now time.Time) error {
+	now = now.In(n.sched.Location())
+	data := dailySummaryData{Since: now.Add(-summaryWindow)}
+
+	posted, err := n.rpo.GetPostsByStatus(ctx, repo.StatusPosted)
+	if err != nil {
+		return err
+	}
+	for _, post := range posted {
+		if postedAt, ok := post.PostedAt.Get(); ok && postedAt.After(data.Since) {
+			data.Published = append(data.Published, post)
+		}
+	}
+	sort.Slice(data.Published, func(i, j int) bool {
+		return data.Published[i].PostedAt.MustGet().Before(data.Published[j].PostedAt.MustGet())
+	})
+
+	data.Failed, err = n.rpo.GetPostsByStatus(ctx, repo.StatusFailed)
+	if err != nil {
+		return err
+	}
+
+	projections, err := schedule.ProjectQueue(ctx, n.rpo, repo.QueueFeed, n.sched, now)
+	if err != nil {
+		return err
+	}
+	for _, p := range projections {
+		if !p.Pinned {
+			data.Queued++
+		}
+		if !p.PublishAt.IsZero() && p.PublishAt.Before(now.Add(summaryWindow)) {
+			data.Upcoming = append(data.Upcoming, p)
+		}
+	}
+	sort.SliceStable(data.Upcoming, func(i, j int) bool {
+		return data.Upcoming[i].PublishAt.Before(data.Upcoming[j].PublishAt)
+	})
+
+	subject := fmt.Sprintf("isza daily summary for %s: %d published", now.Format("Jan 2"), len(data.Published))
+	return n.Send(ctx, templateDailySummary, subject, data)
+}
+
+// Run sends the daily summary at the configured time until ctx is cancelled.
+// It returns at once if notifications or the summary are disabled.
+func (n *Notifier) Run(ctx context.Context) {
+	if !n.Enabled() || n.cfg.DailySummary == "" {
+		return
+	}
+	for {
+		next := n.nextSummary(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		if err := n.SendDailySummary(ctx, next); err != nil {
+			n.logger.Errorw("error sending daily summary", "error", err)
+			continue
+		}
+		n.logger.Infow("daily summary sent", "to", n.cfg.To)
+	}
+}
+
+// nextSummary returns the first daily summary time strictly after t.
+func (n *Notifier) nextSummary(t time.Time) time.Time {
+	// The time was validated with the config.
+	at, _ := time.Parse("15:04", n.cfg.DailySummary)
+	local := t.In(n.sched.Location())
+	next := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, n.sched.Location())
+	if !next.After(t) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, at.Hour(), at.Minute(), 0, 0, n.sched.Location())
+	}
+	return next
+}
@@ -0,0 +1,309 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/assets"
+	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
+)
+
+// TLSMode is how the connection to the SMTP server is secured.
+type TLSMode string
+
+const (
+	// TLSNone sends in plain text; only suitable for a local relay or sink.
+	TLSNone TLSMode = "none"
+	// TLSStartTLS upgrades a plain connection with STARTTLS, usually on
+	// port 587.
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit connects over TLS from the start, usually on port 465.
+	TLSImplicit TLSMode = "tls"
+)
+
+// sendTimeout bounds a single delivery to the SMTP server.
+const sendTimeout = 30 * time.Second
+
+var ErrInvalidConfig = fmt.Errorf("invalid smtp config")
+
+func ParseTLSMode(s string) (TLSMode, error) {
+	switch mode := TLSMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case TLSNone, TLSStartTLS, TLSImplicit:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: unknown tls mode %q", ErrInvalidConfig, s)
+	}
+}
+
+// Config is where and to whom notifications are sent. Notifications are
+// disabled if Host is empty.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLS      TLSMode
+	From     string
+	To       []string
+	// DailySummary is the time of day, e.g. "20:00" in the instance time
+	// zone, at which the daily summary is sent. Empty disables it.
+	DailySummary string
+}
+
+func (c *Config) validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("%w: invalid port %d", ErrInvalidConfig, c.Port)
+	}
+	if _, err := ParseTLSMode(string(c.TLS)); err != nil {
+		return err
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("%w: invalid sender %q", ErrInvalidConfig, c.From)
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("%w: at least one recipient is required", ErrInvalidConfig)
+	}
+	for _, to := range c.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("%w: invalid recipient %q", ErrInvalidConfig, to)
+		}
+	}
+	if c.DailySummary != "" {
+		if _, err := time.Parse("15:04", c.DailySummary); err != nil {
+			return fmt.Errorf("%w: invalid daily summary time %q", ErrInvalidConfig, c.DailySummary)
+		}
+	}
+	return nil
+}
+
+var (
+	textTmpl = texttemplate.Must(texttemplate.New("").Funcs(texttemplate.FuncMap{
+		"displayTime": displayTime,
+	}).ParseFS(assets.Templates, "templates/email/*.txt.tmpl"))
+	htmlTmpl = htmltemplate.Must(htmltemplate.New("").Funcs(htmltemplate.FuncMap{
+		"displayTime": displayTime,
+	}).ParseFS(assets.Templates, "templates/email/*.html.tmpl"))
+)
+
+func displayTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02 15:04 MST")
+}
+
+// Notifier emails notifications to the configured recipients. A Notifier
+// with no SMTP host is disabled and drops every message.
+type Notifier struct {
+	logger *zap.SugaredLogger
+	rpo    *repo.Repo
+	sched  *schedule.Schedule
+	cfg    Config
+}
+
+// New returns a Notifier for cfg. Times in messages are shown in the
+// schedule's time zone.
+func New(
+	logger *zap.SugaredLogger,
+	rpo *repo.Repo,
+	sched *schedule.Schedule,
+	cfg Config,
+) (*Notifier, error) {
+	if cfg.Host != "" {
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &Notifier{
+		logger: logger,
+		rpo:    rpo,
+		sched:  sched,
+		cfg:    cfg,
+	}, nil
+}
+
+// Enabled reports whether an SMTP server is configured.
+func (n *Notifier) Enabled() bool {
+	return n.cfg.Host != ""
+}
+
+// Notify renders the plain-text and HTML templates named name and emails them
+// to the recipients. Errors are logged rather than returned so that
+// notifications never fail the action that caused them.
+func (n *Notifier) Notify(ctx context.Context, name, subject string, data any) {
+	if !n.Enabled() {
+		return
+	}
+	if err := n.Send(ctx, name, subject, data); err != nil {
+		n.logger.Errorw("error sending email notification", "template", name, "error", err)
+		return
+	}
+	n.logger.Infow("email notification sent", "template", name, "to", n.cfg.To)
+}
+
+// Send is like Notify but returns the error.
+func (n *Notifier) Send(ctx context.Context, name, subject string, data any) error {
+	if !n.Enabled() {
+		return fmt.Errorf("%w: no smtp host configured", ErrInvalidConfig)
+	}
+	msg, err := n.message(name, subject, data)
+	if err != nil {
+		return err
+	}
+	return n.send(ctx, msg)
+}
+
+// templateData wraps the data of every template with the fields they share.
+type templateData struct {
+	Subject string
+	TZ      *time.Location
+	Data    any
+}
+
+// message builds a multipart/alternative message with a plain-text and an
+// HTML part.
+func (n *Notifier) message(name, subject string, data any) ([]byte, error) {
+	td := templateData{Subject: subject, TZ: n.sched.Location(), Data: data}
+	var text, html bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&text, name+".txt.tmpl", td); err != nil {
+		return nil, fmt.Errorf("error rendering text email: %w", err)
+	}
+	if err := htmlTmpl.ExecuteTemplate(&html, name+".html.tmpl", td); err != nil {
+		return nil, fmt.Errorf("error rendering html email: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error creating email part: %w", err)
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write(p.content); err != nil {
+			return nil, fmt.Errorf("error writing email part: %w", err)
+		}
+		if err := qw.Close(); err != nil {
+			return nil, fmt.Errorf("error writing email part: %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing email body: %w", err)
+	}
+
+	var msg bytes.Buffer
+	header := func(k, v string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", k, v)
+	}
+	header("From", n.cfg.From)
+	header("To", strings.Join(n.cfg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(n.cfg.From))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func messageID(from string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	domain := "isza"
+	if i := strings.LastIndex(address(from), "@"); i >= 0 {
+		domain = address(from)[i+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
+func (n *Notifier) send(ctx context.Context, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: n.cfg.Host}
+	var conn net.Conn
+	var err error
+	if n.cfg.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("error connecting to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error starting smtp session: %w", err)
+	}
+	defer c.Close()
+
+	if n.cfg.TLS == TLSStartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("error starting tls: %w", err)
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)); err != nil {
+			return fmt.Errorf("error authenticating: %w", err)
+		}
+	}
+	if err := c.Mail(address(n.cfg.From)); err != nil {
+		return fmt.Errorf("error setting sender: %w", err)
+	}
+	for _, to := range n.cfg.To {
+		if err := c.Rcpt(address(to)); err != nil {
+			return fmt.Errorf("error adding recipient %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("error starting message: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("error writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error sending message: %w", err)
+	}
+	return c.Quit()
+}
+
+// address returns the bare address of a mailbox such as "Name <addr>". The
+// config has already been validated, so parsing cannot fail.
+func address(mailbox string) string {
+	a, _ := mail.ParseAddress(mailbox)
+	return a.Address
+}
@@ -0,0 +1,414 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/idempotency"
+	"github.com/btschwartz12/isza/instagram"
+	"github.com/btschwartz12/isza/logging"
+	"github.com/btschwartz12/isza/metrics"
+	"github.com/btschwartz12/isza/notify"
+	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
+	"github.com/btschwartz12/isza/tracing"
+	"github.com/btschwartz12/isza/webhook"
+)
+
+// schedulerInterval is how often the scheduler checks for pinned posts that
+// are due.
+const schedulerInterval = time.Minute
+
+// killWait is how long Drain waits for killed publishes to record their
+// result.
+const killWait = 5 * time.Second
+
+// leaseSlack is how long a publish lease outlives the publish timeout, to
+// cover killing the post script and recording the result.
+const leaseSlack = time.Minute
+
+var (
+	ErrBlackout     = fmt.Errorf("publishing is paused by a blackout")
+	ErrShuttingDown = fmt.Errorf("publisher is shutting down")
+	ErrNoComment    = fmt.Errorf("no failed first comment to retry")
+)
+
+type Publisher struct {
+	logger          *zap.SugaredLogger
+	rpo             *repo.Repo
+	sched           *schedule.Schedule
+	hooks           *webhook.Dispatcher
+	notifier        *notify.Notifier
+	instaUsername   string
+	instaPassword   string
+	instaWorkingDir string
+	timeout         time.Duration
+
+	// stop is cancelled when draining gives up, to kill the post scripts
+	// still running.
+	stop    context.Context
+	stopAll context.CancelFunc
+	// mu guards draining and inflight, the IDs of the posts being
+	// published; wg counts them.
+	mu       sync.Mutex
+	draining bool
+	inflight map[int64]struct{}
+	wg       sync.WaitGroup
+}
+
+func New(
+	logger *zap.SugaredLogger,
+	rpo *repo.Repo,
+	sched *schedule.Schedule,
+	hooks *webhook.Dispatcher,
+	notifier *notify.Notifier,
+	instaUsername,
+	instaPassword,
+	instaWorkingDir string,
+	timeout time.Duration,
+) *Publisher {
+	stop, stopAll := context.WithCancel(context.Background())
+	return &Publisher{
+		logger:          logger,
+		rpo:             rpo,
+		sched:           sched,
+		hooks:           hooks,
+		notifier:        notifier,
+		instaUsername:   instaUsername,
+		instaPassword:   instaPassword,
+		instaWorkingDir: instaWorkingDir,
+		timeout:         timeout,
+		stop:            stop,
+		stopAll:         stopAll,
+		inflight:        make(map[int64]struct{}),
+	}
+}
+
+// Username returns the Instagram account posts are published to.
+func (p *Publisher) Username() string {
+	return p.instaUsername
+}
+
+// PublishNext publishes the pinned post of queue that is most overdue, or the
+// post at the front of queue if no pinned post is due. It returns
+// repo.ErrPostNotFound if there is nothing to publish, an error wrapping
+// ErrBlackout if a blackout is in effect, an error wrapping
+// repo.ErrPostClaimed if another caller got to the post first, or
+// ErrShuttingDown once the publisher is draining.
+func (p *Publisher) PublishNext(ctx context.Context, queue repo.Queue) (*repo.Post, error) {
+	if err := p.checkBlackouts(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+	post, err := p.rpo.GetDuePinnedPost(ctx, queue, time.Now())
+	if errors.Is(err, repo.ErrPostNotFound) {
+		post, err = p.rpo.GetPostToPost(ctx, queue)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return post, p.Publish(ctx, post)
+}
+
+// Publish publishes a queued post, recording it as posted or failed and
+// emitting the matching webhook event. Failures are also emailed. It returns
+// ErrShuttingDown once the publisher is draining.
+//
+// The post is claimed before it is published, so a post is published at most
+// once even if several callers race for it; the losers get an error wrapping
+// repo.ErrPostClaimed.
+//
+// The post script is killed if it runs past the publish timeout or ctx is
+// cancelled. The post is then marked as interrupted, since it may or may not
+// have gone out, and the error wraps instagram.ErrTimeout or
+// instagram.ErrCancelled.
+func (p *Publisher) Publish(ctx context.Context, post *repo.Post) (err error) {
+	if err := p.begin(post.ID); err != nil {
+		return err
+	}
+	defer p.finish(post.ID)
+
+	ctx, span := tracing.Start(ctx, "publisher.Publish", attribute.Int64("post.id", post.ID))
+	defer func() { tracing.End(span, err) }()
+	execCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	defer context.AfterFunc(p.stop, cancel)()
+	// The result must be recorded even if ctx is cancelled, or the post
+	// could be published again.
+	ctx = context.WithoutCancel(ctx)
+	logger := logging.FromContext(ctx, p.logger)
+
+	err = p.rpo.ClaimPost(ctx, post.ID, time.Now().Add(p.timeout+leaseSlack))
+	if err != nil {
+		return err
+	}
+	// From here on the post may go out, so a retried request must not
+	// publish another one.
+	idempotency.Commit(ctx)
+
+	metrics.PublishAttempts.Inc()
+	start := time.Now()
+	mediaID, err := instagram.ExecutePost(execCtx, logger, p.rpo, p.instaWorkingDir, p.instaUsername, p.instaPassword, post)
+	if err != nil {
+		result, status := "failure", repo.StatusFailed
+		switch {
+		case errors.Is(err, instagram.ErrTimeout):
+			result, status = "timeout", repo.StatusInterrupted
+		case errors.Is(err, instagram.ErrCancelled):
+			result, status = "cancelled", repo.StatusInterrupted
+		}
+		metrics.PublishFailures.Inc()
+		metrics.PublishDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+		if terr := p.rpo.TransitionPost(ctx, post.ID, status); terr != nil {
+			logger.Errorw("error recording failed publish", "id", post.ID, "status", status, "error", terr)
+		}
+		if current, gerr := p.rpo.GetPost(ctx, post.ID); gerr == nil {
+			post = current
+		}
+		p.hooks.EmitPost(ctx, webhook.EventPostPublishFailed, post, err)
+		p.notifier.PublishFailed(ctx, post, err)
+		return err
+	}
+
+	metrics.PublishSuccesses.Inc()
+	metrics.PublishDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+
+	err = p.rpo.TransitionPost(ctx, post.ID, repo.StatusPosted)
+	if err != nil {
+		return fmt.Errorf("error setting post as posted: %w", err)
+	}
+	hasComment := post.FirstComment != ""
+	if err := p.rpo.RecordMediaID(ctx, post.ID, mediaID, hasComment); err != nil {
+		logger.Errorw("error recording media ID", "id", post.ID, "error", err)
+	}
+	p.hooks.EmitPostByID(ctx, webhook.EventPostPublished, post.ID)
+
+	// A failed comment is recorded on the post for a retry; the post itself
+	// went out.
+	if hasComment {
+		p.comment(ctx, post.ID)
+	}
+	return nil
+}
+
+// RetryComment posts the first comment of a published post whose comment
+// failed. It returns an error wrapping ErrNoComment if there is no failed
+// comment, the error of the comment script if it fails again, or
+// ErrShuttingDown once the publisher is draining.
+func (p *Publisher) RetryComment(ctx context.Context, id int64) error {
+	post, err := p.rpo.GetPost(ctx, id)
+	if err != nil {
+		return err
+	}
+	if post.Status != repo.StatusPosted || post.FirstComment == "" || post.CommentStatus == repo.CommentPosted {
+		return fmt.Errorf("%w: post %d", ErrNoComment, id)
+	}
+	if err := p.begin(id); err != nil {
+		return err
+	}
+	defer p.finish(id)
+	return p.comment(context.WithoutCancel(ctx), id)
+}
+
+// comment posts the first comment of a published post and records whether
+// it succeeded.
+func (p *Publisher) comment(ctx context.Context, id int64) error {
+	logger := logging.FromContext(ctx, p.logger)
+	post, err := p.rpo.GetPost(ctx, id)
+	if err != nil {
+		return err
+	}
+	execCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	defer context.AfterFunc(p.stop, cancel)()
+
+	if post.MediaID == "" {
+		err = fmt.Errorf("media ID of post %d is unknown", id)
+	} else {
+		err = instagram.ExecuteComment(execCtx, logger, p.instaWorkingDir, p.instaUsername, p.instaPassword, post, post.FirstComment)
+	}
+	if err != nil {
+		logger.Errorw("error posting first comment", "id", id, "error", err)
+	}
+	if rerr := p.rpo.RecordComment(ctx, id, err); rerr != nil {
+		logger.Errorw("error recording first comment", "id", id, "error", rerr)
+	}
+	return err
+}
+
+// Run publishes pinned posts as they come due until ctx is cancelled. Posts
+// that come due during a blackout are published once it ends.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.expireLeases(ctx)
+			// A publish in progress when the loop stops is left for Drain
+			// to wait on rather than killed.
+			p.publishDue(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+// checkBlackouts returns an error wrapping ErrBlackout, naming the blackout
+// and when publishing resumes, if one covers now.
+func (p *Publisher) checkBlackouts(ctx context.Context, now time.Time) error {
+	blackouts, err := p.rpo.GetAllBlackouts(ctx)
+	if err != nil {
+		return err
+	}
+	b, resume, paused := p.sched.Paused(blackouts, now)
+	if !paused {
+		return nil
+	}
+	until := resume.In(p.sched.Location()).Format("2006-01-02 15:04 MST")
+	return fmt.Errorf("%w: %q until %s", ErrBlackout, b.Name, until)
+}
+
+func (p *Publisher) publishDue(ctx context.Context) {
+	if err := p.checkBlackouts(ctx, time.Now()); err != nil {
+		if !errors.Is(err, ErrBlackout) {
+			p.logger.Errorw("error checking blackouts", "error", err)
+		}
+		return
+	}
+	for _, queue := range repo.AllQueues {
+		p.publishDuePinned(ctx, queue)
+	}
+}
+
+// publishDuePinned publishes the due pinned posts of queue, most overdue
+// first.
+func (p *Publisher) publishDuePinned(ctx context.Context, queue repo.Queue) {
+	for {
+		post, err := p.rpo.GetDuePinnedPost(ctx, queue, time.Now())
+		if err != nil {
+			if !errors.Is(err, repo.ErrPostNotFound) {
+				p.logger.Errorw("error getting due pinned post", "error", err)
+			}
+			return
+		}
+		if err := p.Publish(ctx, post); err != nil {
+			if errors.Is(err, repo.ErrPostClaimed) {
+				p.logger.Infow("pinned post is already being published", "id", post.ID)
+				return
+			}
+			if !errors.Is(err, ErrShuttingDown) {
+				p.logger.Errorw("error publishing pinned post", "id", post.ID, "error", err)
+			}
+			return
+		}
+		p.logger.Infow("pinned post published", "id", post.ID)
+	}
+}
+
+func (p *Publisher) begin(id int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.draining {
+		return ErrShuttingDown
+	}
+	if _, ok := p.inflight[id]; ok {
+		return fmt.Errorf("%w: post %d", repo.ErrPostClaimed, id)
+	}
+	p.inflight[id] = struct{}{}
+	p.wg.Add(1)
+	return nil
+}
+
+func (p *Publisher) finish(id int64) {
+	p.mu.Lock()
+	delete(p.inflight, id)
+	p.mu.Unlock()
+	p.wg.Done()
+}
+
+// Drain stops new publishes and waits for those in progress to finish and
+// record their result. If ctx is done first, their post scripts are killed
+// and the posts are marked as interrupted, since they may or may not have
+// gone out.
+func (p *Publisher) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	p.draining = true
+	n := len(p.inflight)
+	p.mu.Unlock()
+	if n > 0 {
+		p.logger.Infow("waiting for publishes to finish", "count", n)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Kill the post scripts and give the publishes a moment to record that
+	// they were interrupted; any that do not are marked here.
+	p.stopAll()
+	select {
+	case <-done:
+		return fmt.Errorf("publishes in progress were killed: %w", ctx.Err())
+	case <-time.After(killWait):
+	}
+
+	p.mu.Lock()
+	ids := make([]int64, 0, len(p.inflight))
+	for id := range p.inflight {
+		ids = append(ids, id)
+	}
+	p.mu.Unlock()
+	for _, id := range ids {
+		p.interrupt(context.WithoutCancel(ctx), id)
+	}
+	return fmt.Errorf("%d publishes did not finish: %w", len(ids), ctx.Err())
+}
+
+// Recover marks the posts left publishing by an unclean exit as interrupted.
+// It must be called before anything is published.
+func (p *Publisher) Recover(ctx context.Context) error {
+	posts, err := p.rpo.GetPostsByStatus(ctx, repo.StatusPublishing)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		p.interrupt(ctx, post.ID)
+	}
+	return nil
+}
+
+// expireLeases marks the posts whose publish lease ran out as interrupted.
+// Their publisher died, or failed to record the result, so they may or may
+// not have gone out.
+func (p *Publisher) expireLeases(ctx context.Context) {
+	posts, err := p.rpo.GetExpiredLeases(ctx, time.Now())
+	if err != nil {
+		p.logger.Errorw("error getting expired publish leases", "error", err)
+		return
+	}
+	for _, post := range posts {
+		p.interrupt(ctx, post.ID)
+	}
+}
+
+func (p *Publisher) interrupt(ctx context.Context, id int64) {
+	if err := p.rpo.TransitionPost(ctx, id, repo.StatusInterrupted); err != nil {
+		p.logger.Errorw("error setting post as interrupted", "id", id, "error", err)
+		return
+	}
+	p.logger.Warnw("publish interrupted, check the account and mark the post as posted or queue it again", "id", id)
+	p.hooks.EmitPostByID(ctx, webhook.EventPostStatusChanged, id)
+}
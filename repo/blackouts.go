@@ -0,0 +1,203 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/isza/repo/db"
+)
+
+// Recurrence is how often a blackout repeats.
+type Recurrence string
+
+const (
+	RecurNone   Recurrence = "none"
+	RecurWeekly Recurrence = "weekly"
+	RecurYearly Recurrence = "yearly"
+)
+
+var (
+	ErrBlackoutNotFound = fmt.Errorf("blackout not found")
+	ErrInvalidBlackout  = fmt.Errorf("invalid blackout")
+)
+
+func ParseRecurrence(s string) (Recurrence, error) {
+	switch r := Recurrence(s); r {
+	case RecurNone, RecurWeekly, RecurYearly:
+		return r, nil
+	case "":
+		return RecurNone, nil
+	default:
+		return "", fmt.Errorf("%w: unknown recurrence %q", ErrInvalidBlackout, s)
+	}
+}
+
+// Blackout is a period during which nothing is published automatically. A
+// recurring blackout repeats its first occurrence, StartsAt to EndsAt, every
+// week or year on the wall clock.
+type Blackout struct {
+	ID         int64
+	Name       string
+	StartsAt   time.Time
+	EndsAt     time.Time
+	Recurrence Recurrence
+	Timestamp  time.Time
+}
+
+func (b *Blackout) fromDb(row *db.Blackout) {
+	b.ID = row.ID
+	b.Name = row.Name
+	b.Recurrence = Recurrence(row.Recurrence)
+	b.StartsAt, _ = time.Parse(time.RFC3339, row.StartsAt)
+	b.EndsAt, _ = time.Parse(time.RFC3339, row.EndsAt)
+	b.Timestamp, _ = time.Parse(time.RFC3339, row.Timestamp)
+}
+
+// In returns the blackout with its times in loc.
+func (b Blackout) In(loc *time.Location) Blackout {
+	b.StartsAt = b.StartsAt.In(loc)
+	b.EndsAt = b.EndsAt.In(loc)
+	b.Timestamp = b.Timestamp.In(loc)
+	return b
+}
+
+// Covers reports whether t falls within an occurrence of the blackout, and
+// if so when that occurrence ends. Recurrences are computed in loc so that
+// they keep their wall clock times across DST changes.
+func (b *Blackout) Covers(t time.Time, loc *time.Location) (time.Time, bool) {
+	start := b.StartsAt.In(loc)
+	end := b.EndsAt.In(loc)
+	if t.Before(start) {
+		return time.Time{}, false
+	}
+
+	var first, last int
+	var shift func(time.Time, int) time.Time
+	switch b.Recurrence {
+	case RecurWeekly:
+		n := int(t.Sub(start) / (7 * 24 * time.Hour))
+		first, last = n-1, n+1
+		shift = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
+	case RecurYearly:
+		n := t.In(loc).Year() - start.Year()
+		first, last = n-1, n
+		shift = func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }
+	default:
+		first, last = 0, 0
+		shift = func(t time.Time, _ int) time.Time { return t }
+	}
+
+	for n := max(first, 0); n <= last; n++ {
+		s, e := shift(start, n), shift(end, n)
+		if !t.Before(s) && t.Before(e) {
+			return e, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func validateBlackout(name string, start, end time.Time, recurrence Recurrence) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidBlackout)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("%w: end must be after start", ErrInvalidBlackout)
+	}
+	switch recurrence {
+	case RecurWeekly:
+		if end.Sub(start) > 7*24*time.Hour {
+			return fmt.Errorf("%w: a weekly blackout cannot be longer than a week", ErrInvalidBlackout)
+		}
+	case RecurYearly:
+		if end.After(start.AddDate(1, 0, 0)) {
+			return fmt.Errorf("%w: a yearly blackout cannot be longer than a year", ErrInvalidBlackout)
+		}
+	}
+	return nil
+}
+
+func (r *Repo) InsertBlackout(ctx context.Context, name string, start, end time.Time, recurrence Recurrence) (*Blackout, error) {
+	name = strings.TrimSpace(name)
+	if err := validateBlackout(name, start, end, recurrence); err != nil {
+		return nil, err
+	}
+	q := db.New(r.db)
+	row, err := q.InsertBlackout(ctx, db.InsertBlackoutParams{
+		Name:       name,
+		StartsAt:   zulu(start),
+		EndsAt:     zulu(end),
+		Recurrence: string(recurrence),
+		Timestamp:  zulu(time.Now()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error inserting blackout: %w", err)
+	}
+	b := &Blackout{}
+	b.fromDb(&row)
+	return b, nil
+}
+
+func (r *Repo) GetAllBlackouts(ctx context.Context) ([]Blackout, error) {
+	q := db.New(r.db)
+	rows, err := q.GetAllBlackouts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting all blackouts: %w", err)
+	}
+	blackouts := make([]Blackout, len(rows))
+	for i, row := range rows {
+		blackouts[i].fromDb(&row)
+	}
+	return blackouts, nil
+}
+
+func (r *Repo) GetBlackout(ctx context.Context, id int64) (*Blackout, error) {
+	q := db.New(r.db)
+	row, err := q.GetBlackoutById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBlackoutNotFound
+		}
+		return nil, fmt.Errorf("error getting blackout: %w", err)
+	}
+	b := &Blackout{}
+	b.fromDb(&row)
+	return b, nil
+}
+
+func (r *Repo) UpdateBlackout(ctx context.Context, id int64, name string, start, end time.Time, recurrence Recurrence) error {
+	name = strings.TrimSpace(name)
+	if err := validateBlackout(name, start, end, recurrence); err != nil {
+		return err
+	}
+	q := db.New(r.db)
+	n, err := q.UpdateBlackout(ctx, db.UpdateBlackoutParams{
+		Name:       name,
+		StartsAt:   zulu(start),
+		EndsAt:     zulu(end),
+		Recurrence: string(recurrence),
+		ID:         id,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating blackout: %w", err)
+	}
+	if n == 0 {
+		return ErrBlackoutNotFound
+	}
+	return nil
+}
+
+func (r *Repo) DeleteBlackout(ctx context.Context, id int64) error {
+	q := db.New(r.db)
+	n, err := q.DeleteBlackout(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting blackout: %w", err)
+	}
+	if n == 0 {
+		return ErrBlackoutNotFound
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlackoutCovers(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("loading America/New_York: %v", err)
+	}
+	at := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, ny)
+	}
+
+	once := Blackout{StartsAt: at(2024, 5, 1, 9, 0), EndsAt: at(2024, 5, 1, 17, 0), Recurrence: RecurNone}
+	// Saturday mornings, starting before the clocks go forward.
+	weekly := Blackout{StartsAt: at(2024, 3, 2, 8, 0), EndsAt: at(2024, 3, 2, 12, 0), Recurrence: RecurWeekly}
+	// Christmas, and the turn of the year, which spans two calendar years.
+	christmas := Blackout{StartsAt: at(2023, 12, 24, 0, 0), EndsAt: at(2023, 12, 26, 0, 0), Recurrence: RecurYearly}
+	newYear := Blackout{StartsAt: at(2023, 12, 31, 18, 0), EndsAt: at(2024, 1, 2, 0, 0), Recurrence: RecurYearly}
+
+	tests := []struct {
+		name     string
+		blackout Blackout
+		t        time.Time
+		want     time.Time // zero if not covered
+	}{
+		{"once before", once, at(2024, 5, 1, 8, 59), time.Time{}},
+		{"once at start", once, at(2024, 5, 1, 9, 0), at(2024, 5, 1, 17, 0)},
+		{"once during", once, at(2024, 5, 1, 12, 0), at(2024, 5, 1, 17, 0)},
+		{"once at end", once, at(2024, 5, 1, 17, 0), time.Time{}},
+		{"once a week later", once, at(2024, 5, 8, 12, 0), time.Time{}},
+
+		{"weekly before first", weekly, at(2024, 2, 24, 9, 0), time.Time{}},
+		{"weekly first", weekly, at(2024, 3, 2, 9, 0), at(2024, 3, 2, 12, 0)},
+		{"weekly between", weekly, at(2024, 3, 6, 9, 0), time.Time{}},
+		{"weekly after clocks go forward", weekly, at(2024, 3, 16, 8, 30), at(2024, 3, 16, 12, 0)},
+		{"weekly before wall clock start", weekly, at(2024, 3, 16, 7, 30), time.Time{}},
+		{"weekly months later", weekly, at(2024, 11, 9, 11, 59), at(2024, 11, 9, 12, 0)},
+		{"weekly at end", weekly, at(2024, 11, 9, 12, 0), time.Time{}},
+
+		{"yearly first", christmas, at(2023, 12, 25, 12, 0), at(2023, 12, 26, 0, 0)},
+		{"yearly later year", christmas, at(2026, 12, 25, 12, 0), at(2026, 12, 26, 0, 0)},
+		{"yearly off season", christmas, at(2026, 7, 4, 12, 0), time.Time{}},
+		{"yearly across new year", newYear, at(2026, 1, 1, 12, 0), at(2026, 1, 2, 0, 0)},
+		{"yearly new year's eve", newYear, at(2025, 12, 31, 20, 0), at(2026, 1, 2, 0, 0)},
+		{"yearly before new year's eve", newYear, at(2025, 12, 31, 17, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, ok := tt.blackout.Covers(tt.t, ny)
+			if ok != !tt.want.IsZero() {
+				t.Fatalf("Covers(%v) covered = %v, want %v", tt.t, ok, !tt.want.IsZero())
+			}
+			if ok && !end.Equal(tt.want) {
+				t.Errorf("Covers(%v) end = %v, want %v", tt.t, end, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBlackout(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		end        time.Time
+		recurrence Recurrence
+		wantErr    bool
+	}{
+		{"one off", start.Add(time.Hour), RecurNone, false},
+		{"ends at start", start, RecurNone, true},
+		{"ends before start", start.Add(-time.Hour), RecurNone, true},
+		{"week long weekly", start.AddDate(0, 0, 7), RecurWeekly, false},
+		{"weekly over a week", start.AddDate(0, 0, 7).Add(time.Minute), RecurWeekly, true},
+		{"yearly over a year", start.AddDate(1, 0, 0).Add(time.Minute), RecurYearly, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBlackout("blackout", start, tt.end, tt.recurrence)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateBlackout() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btschwartz12/isza/repo/db"
+)
+
+// CommentStatus is how posting a post's first comment went. It is empty
+// until a post with a first comment is published.
+type CommentStatus string
+
+const (
+	CommentPending CommentStatus = "pending"
+	CommentPosted  CommentStatus = "posted"
+	CommentFailed  CommentStatus = "failed"
+)
+
+// UpdatePostFirstComment sets the comment posted on a post right after it
+// is published. An empty comment means none is posted.
+func (r *Repo) UpdatePostFirstComment(ctx context.Context, id int64, comment string) error {
+	if _, err := r.GetPost(ctx, id); err != nil {
+		return err
+	}
+	q := db.New(r.db)
+	err := q.UpdatePostFirstComment(ctx, db.UpdatePostFirstCommentParams{
+		FirstComment: comment,
+		ID:           id,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating post first comment: %w", err)
+	}
+	return nil
+}
+
+// RecordMediaID stores the Instagram ID of a post's media once it is
+// published. The comment status becomes pending if the post has a first
+// comment, and is cleared otherwise.
+func (r *Repo) RecordMediaID(ctx context.Context, id int64, mediaID string, hasComment bool) error {
+	var status CommentStatus
+	if hasComment {
+		status = CommentPending
+	}
+	q := db.New(r.db)
+	err := q.UpdatePostMediaID(ctx, db.UpdatePostMediaIDParams{
+		MediaID:       mediaID,
+		CommentStatus: string(status),
+		ID:            id,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording post media ID: %w", err)
+	}
+	return nil
+}
+
+// RecordComment records whether posting a post's first comment succeeded,
+// with commentErr as the reason if it did not.
+func (r *Repo) RecordComment(ctx context.Context, id int64, commentErr error) error {
+	status, reason := CommentPosted, ""
+	if commentErr != nil {
+		status, reason = CommentFailed, commentErr.Error()
+	}
+	q := db.New(r.db)
+	err := q.UpdatePostCommentStatus(ctx, db.UpdatePostCommentStatusParams{
+		CommentStatus: string(status),
+		CommentError:  reason,
+		ID:            id,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording post comment: %w", err)
+	}
+	return nil
+}
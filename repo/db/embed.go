@@ -1,8 +1,11 @@
 package db
 
 import (
-	_ "embed"
+	"embed"
 )
 
-//go:embed sql/schema.sql
-var Schema []byte
+// Migrations holds the numbered schema migrations, applied in order on
+// startup.
+//
+//go:embed sql/migrations/*.sql
+var Migrations embed.FS
@@ -15,6 +15,96 @@ type Post struct {
 	Timestamp      string
 	Position       int64
 	PhotoCount     int64
-	IsPosted       int64
 	PostedAt       sql.NullString
+	Status         string
+	ScheduledAt    sql.NullString
+	LeaseExpiresAt sql.NullString
+	PostType       string
+	CoverFilename  string
+	Queue          string
+	LocationName   string
+	LocationLat    sql.NullFloat64
+	LocationLng    sql.NullFloat64
+	FirstComment   string
+	MediaID        string
+	CommentStatus  string
+	CommentError   string
+}
+
+type HashtagSet struct {
+	ID        int64
+	Name      string
+	Hashtags  string
+	Timestamp string
+}
+
+type PostHashtagSet struct {
+	PostID       int64
+	HashtagSetID int64
+}
+
+type HashtagUsage struct {
+	PostID  int64
+	Hashtag string
+}
+
+type CaptionVariable struct {
+	Account string
+	Key     string
+	Value   string
+}
+
+type Blackout struct {
+	ID         int64
+	Name       string
+	StartsAt   string
+	EndsAt     string
+	Recurrence string
+	Timestamp  string
+}
+
+type Webhook struct {
+	ID        int64
+	URL       string
+	Secret    string
+	Events    string
+	Timestamp string
+}
+
+type WebhookDelivery struct {
+	ID            int64
+	WebhookID     int64
+	Event         string
+	Payload       string
+	Status        string
+	Attempts      int64
+	ResponseCode  int64
+	Error         string
+	NextAttemptAt sql.NullString
+	Timestamp     string
+}
+
+type IdempotencyKey struct {
+	Key         string
+	Fingerprint string
+	StatusCode  int64
+	Header      string
+	Body        []byte
+	ExpiresAt   string
+	Timestamp   string
+}
+
+type PostImage struct {
+	PostID   int64
+	Filename string
+	AltText  string
+}
+
+type PostUserTag struct {
+	ID       int64
+	PostID   int64
+	Filename string
+	Username string
+	X        float64
+	Y        float64
 }
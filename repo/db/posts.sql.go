@@ -10,6 +10,47 @@ import (
 	"database/sql"
 )
 
+const claimPost = `-- name: ClaimPost :execrows
+UPDATE
+    posts
+SET
+    status = 'publishing',
+    position = 0,
+    lease_expires_at = ?
+WHERE
+    id = ?
+    AND status = 'queued'
+`
+
+type ClaimPostParams struct {
+	LeaseExpiresAt sql.NullString
+	ID             int64
+}
+
+func (q *Queries) ClaimPost(ctx context.Context, arg ClaimPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimPost, arg.LeaseExpiresAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countPostedPosts = `-- name: CountPostedPosts :one
+SELECT
+    COUNT(*)
+FROM
+    posts
+WHERE
+    status = 'posted'
+`
+
+func (q *Queries) CountPostedPosts(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostedPosts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deletePost = `-- name: DeletePost :exec
 DELETE FROM
     posts
@@ -24,7 +65,7 @@ func (q *Queries) DeletePost(ctx context.Context, id int64) error {
 
 const getAllPosts = `-- name: GetAllPosts :many
 SELECT
-    id, image_filenames, caption, timestamp, position, photo_count, is_posted, posted_at
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
 FROM
     posts
 `
@@ -45,8 +86,125 @@ func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
 			&i.Timestamp,
 			&i.Position,
 			&i.PhotoCount,
-			&i.IsPosted,
 			&i.PostedAt,
+			&i.Status,
+			&i.ScheduledAt,
+			&i.LeaseExpiresAt,
+			&i.PostType,
+			&i.CoverFilename,
+			&i.Queue,
+			&i.LocationName,
+			&i.LocationLat,
+			&i.LocationLng,
+			&i.FirstComment,
+			&i.MediaID,
+			&i.CommentStatus,
+			&i.CommentError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDuePinnedPost = `-- name: GetDuePinnedPost :one
+SELECT
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
+FROM
+    posts
+WHERE
+    status = 'queued'
+    AND scheduled_at IS NOT NULL
+    AND scheduled_at <= ?
+    AND queue = ?
+ORDER BY
+    scheduled_at ASC
+LIMIT
+    1
+`
+
+type GetDuePinnedPostParams struct {
+	ScheduledAt sql.NullString
+	Queue       string
+}
+
+func (q *Queries) GetDuePinnedPost(ctx context.Context, arg GetDuePinnedPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getDuePinnedPost, arg.ScheduledAt, arg.Queue)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.ImageFilenames,
+		&i.Caption,
+		&i.Timestamp,
+		&i.Position,
+		&i.PhotoCount,
+		&i.PostedAt,
+		&i.Status,
+		&i.ScheduledAt,
+		&i.LeaseExpiresAt,
+		&i.PostType,
+		&i.CoverFilename,
+		&i.Queue,
+		&i.LocationName,
+		&i.LocationLat,
+		&i.LocationLng,
+		&i.FirstComment,
+		&i.MediaID,
+		&i.CommentStatus,
+		&i.CommentError,
+	)
+	return i, err
+}
+
+const getExpiredLeases = `-- name: GetExpiredLeases :many
+SELECT
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
+FROM
+    posts
+WHERE
+    status = 'publishing'
+    AND lease_expires_at <= ?
+ORDER BY
+    id ASC
+`
+
+func (q *Queries) GetExpiredLeases(ctx context.Context, leaseExpiresAt sql.NullString) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredLeases, leaseExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImageFilenames,
+			&i.Caption,
+			&i.Timestamp,
+			&i.Position,
+			&i.PhotoCount,
+			&i.PostedAt,
+			&i.Status,
+			&i.ScheduledAt,
+			&i.LeaseExpiresAt,
+			&i.PostType,
+			&i.CoverFilename,
+			&i.Queue,
+			&i.LocationName,
+			&i.LocationLat,
+			&i.LocationLng,
+			&i.FirstComment,
+			&i.MediaID,
+			&i.CommentStatus,
+			&i.CommentError,
 		); err != nil {
 			return nil, err
 		}
@@ -67,23 +225,82 @@ SELECT
 FROM
     posts
 WHERE
-    is_posted = 0
+    status = 'queued'
+    AND scheduled_at IS NULL
+    AND queue = ?
 ORDER BY
     position DESC
 LIMIT
     1
 `
 
-func (q *Queries) GetLastPositionOfUnpostedPost(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getLastPositionOfUnpostedPost)
+func (q *Queries) GetLastPositionOfUnpostedPost(ctx context.Context, queue string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLastPositionOfUnpostedPost, queue)
 	var position int64
 	err := row.Scan(&position)
 	return position, err
 }
 
+const getPinnedPosts = `-- name: GetPinnedPosts :many
+SELECT
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
+FROM
+    posts
+WHERE
+    status = 'queued'
+    AND scheduled_at IS NOT NULL
+    AND queue = ?
+ORDER BY
+    scheduled_at ASC
+`
+
+func (q *Queries) GetPinnedPosts(ctx context.Context, queue string) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPinnedPosts, queue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImageFilenames,
+			&i.Caption,
+			&i.Timestamp,
+			&i.Position,
+			&i.PhotoCount,
+			&i.PostedAt,
+			&i.Status,
+			&i.ScheduledAt,
+			&i.LeaseExpiresAt,
+			&i.PostType,
+			&i.CoverFilename,
+			&i.Queue,
+			&i.LocationName,
+			&i.LocationLat,
+			&i.LocationLng,
+			&i.FirstComment,
+			&i.MediaID,
+			&i.CommentStatus,
+			&i.CommentError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostById = `-- name: GetPostById :one
 SELECT
-    id, image_filenames, caption, timestamp, position, photo_count, is_posted, posted_at
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
 FROM
     posts
 WHERE
@@ -100,25 +317,44 @@ func (q *Queries) GetPostById(ctx context.Context, id int64) (Post, error) {
 		&i.Timestamp,
 		&i.Position,
 		&i.PhotoCount,
-		&i.IsPosted,
 		&i.PostedAt,
+		&i.Status,
+		&i.ScheduledAt,
+		&i.LeaseExpiresAt,
+		&i.PostType,
+		&i.CoverFilename,
+		&i.Queue,
+		&i.LocationName,
+		&i.LocationLat,
+		&i.LocationLng,
+		&i.FirstComment,
+		&i.MediaID,
+		&i.CommentStatus,
+		&i.CommentError,
 	)
 	return i, err
 }
 
 const getPostByPosition = `-- name: GetPostByPosition :one
 SELECT
-    id, image_filenames, caption, timestamp, position, photo_count, is_posted, posted_at
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
 FROM
     posts
 WHERE
     position = ?
 AND
-    is_posted = 0
+    queue = ?
+AND
+    status = 'queued'
 `
 
-func (q *Queries) GetPostByPosition(ctx context.Context, position int64) (Post, error) {
-	row := q.db.QueryRowContext(ctx, getPostByPosition, position)
+type GetPostByPositionParams struct {
+	Position int64
+	Queue    string
+}
+
+func (q *Queries) GetPostByPosition(ctx context.Context, arg GetPostByPositionParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByPosition, arg.Position, arg.Queue)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -127,26 +363,40 @@ func (q *Queries) GetPostByPosition(ctx context.Context, position int64) (Post,
 		&i.Timestamp,
 		&i.Position,
 		&i.PhotoCount,
-		&i.IsPosted,
 		&i.PostedAt,
+		&i.Status,
+		&i.ScheduledAt,
+		&i.LeaseExpiresAt,
+		&i.PostType,
+		&i.CoverFilename,
+		&i.Queue,
+		&i.LocationName,
+		&i.LocationLat,
+		&i.LocationLng,
+		&i.FirstComment,
+		&i.MediaID,
+		&i.CommentStatus,
+		&i.CommentError,
 	)
 	return i, err
 }
 
 const getPostToPost = `-- name: GetPostToPost :one
 SELECT
-    id, image_filenames, caption, timestamp, position, photo_count, is_posted, posted_at
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
 FROM
     posts
 WHERE
-    is_posted = 0
+    status = 'queued'
+    AND scheduled_at IS NULL
     AND position = 1
+    AND queue = ?
 LIMIT
     1
 `
 
-func (q *Queries) GetPostToPost(ctx context.Context) (Post, error) {
-	row := q.db.QueryRowContext(ctx, getPostToPost)
+func (q *Queries) GetPostToPost(ctx context.Context, queue string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostToPost, queue)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -155,25 +405,95 @@ func (q *Queries) GetPostToPost(ctx context.Context) (Post, error) {
 		&i.Timestamp,
 		&i.Position,
 		&i.PhotoCount,
-		&i.IsPosted,
 		&i.PostedAt,
+		&i.Status,
+		&i.ScheduledAt,
+		&i.LeaseExpiresAt,
+		&i.PostType,
+		&i.CoverFilename,
+		&i.Queue,
+		&i.LocationName,
+		&i.LocationLat,
+		&i.LocationLng,
+		&i.FirstComment,
+		&i.MediaID,
+		&i.CommentStatus,
+		&i.CommentError,
 	)
 	return i, err
 }
 
+const getPostsByStatus = `-- name: GetPostsByStatus :many
+SELECT
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
+FROM
+    posts
+WHERE
+    status = ?
+ORDER BY
+    position ASC,
+    id ASC
+`
+
+func (q *Queries) GetPostsByStatus(ctx context.Context, status string) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByStatus, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImageFilenames,
+			&i.Caption,
+			&i.Timestamp,
+			&i.Position,
+			&i.PhotoCount,
+			&i.PostedAt,
+			&i.Status,
+			&i.ScheduledAt,
+			&i.LeaseExpiresAt,
+			&i.PostType,
+			&i.CoverFilename,
+			&i.Queue,
+			&i.LocationName,
+			&i.LocationLat,
+			&i.LocationLng,
+			&i.FirstComment,
+			&i.MediaID,
+			&i.CommentStatus,
+			&i.CommentError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUnpostedPosts = `-- name: GetUnpostedPosts :many
 SELECT
-    id, image_filenames, caption, timestamp, position, photo_count, is_posted, posted_at
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
 FROM
     posts
 WHERE
-    is_posted = 0
+    status = 'queued'
+    AND scheduled_at IS NULL
+    AND queue = ?
 ORDER BY
     position ASC
 `
 
-func (q *Queries) GetUnpostedPosts(ctx context.Context) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getUnpostedPosts)
+func (q *Queries) GetUnpostedPosts(ctx context.Context, queue string) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getUnpostedPosts, queue)
 	if err != nil {
 		return nil, err
 	}
@@ -188,8 +508,20 @@ func (q *Queries) GetUnpostedPosts(ctx context.Context) ([]Post, error) {
 			&i.Timestamp,
 			&i.Position,
 			&i.PhotoCount,
-			&i.IsPosted,
 			&i.PostedAt,
+			&i.Status,
+			&i.ScheduledAt,
+			&i.LeaseExpiresAt,
+			&i.PostType,
+			&i.CoverFilename,
+			&i.Queue,
+			&i.LocationName,
+			&i.LocationLat,
+			&i.LocationLng,
+			&i.FirstComment,
+			&i.MediaID,
+			&i.CommentStatus,
+			&i.CommentError,
 		); err != nil {
 			return nil, err
 		}
@@ -206,11 +538,11 @@ func (q *Queries) GetUnpostedPosts(ctx context.Context) ([]Post, error) {
 
 const insertPost = `-- name: InsertPost :one
 INSERT INTO
-    posts (image_filenames, caption, timestamp, position, photo_count, is_posted)
+    posts (image_filenames, caption, timestamp, position, photo_count, status, post_type, cover_filename, queue, first_comment)
 VALUES
-    (?, ?, ?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING
-    id, image_filenames, caption, timestamp, position, photo_count, is_posted, posted_at
+    id, image_filenames, caption, timestamp, position, photo_count, posted_at, status, scheduled_at, lease_expires_at, post_type, cover_filename, queue, location_name, location_lat, location_lng, first_comment, media_id, comment_status, comment_error
 `
 
 type InsertPostParams struct {
@@ -219,7 +551,11 @@ type InsertPostParams struct {
 	Timestamp      string
 	Position       int64
 	PhotoCount     int64
-	IsPosted       int64
+	Status         string
+	PostType       string
+	CoverFilename  string
+	Queue          string
+	FirstComment   string
 }
 
 func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, error) {
@@ -229,7 +565,11 @@ func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, e
 		arg.Timestamp,
 		arg.Position,
 		arg.PhotoCount,
-		arg.IsPosted,
+		arg.Status,
+		arg.PostType,
+		arg.CoverFilename,
+		arg.Queue,
+		arg.FirstComment,
 	)
 	var i Post
 	err := row.Scan(
@@ -239,56 +579,162 @@ func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, e
 		&i.Timestamp,
 		&i.Position,
 		&i.PhotoCount,
-		&i.IsPosted,
 		&i.PostedAt,
+		&i.Status,
+		&i.ScheduledAt,
+		&i.LeaseExpiresAt,
+		&i.PostType,
+		&i.CoverFilename,
+		&i.Queue,
+		&i.LocationName,
+		&i.LocationLat,
+		&i.LocationLng,
+		&i.FirstComment,
+		&i.MediaID,
+		&i.CommentStatus,
+		&i.CommentError,
 	)
 	return i, err
 }
 
-const updateIsPostedValueOfPost = `-- name: UpdateIsPostedValueOfPost :exec
+const updatePostCaption = `-- name: UpdatePostCaption :exec
 UPDATE
     posts
 SET
-    is_posted = ?,
-    position = ?,
-    posted_at = ?
+    caption = ?
 WHERE
     id = ?
 `
 
-type UpdateIsPostedValueOfPostParams struct {
-	IsPosted int64
-	Position int64
-	PostedAt sql.NullString
-	ID       int64
+type UpdatePostCaptionParams struct {
+	Caption string
+	ID      int64
 }
 
-func (q *Queries) UpdateIsPostedValueOfPost(ctx context.Context, arg UpdateIsPostedValueOfPostParams) error {
-	_, err := q.db.ExecContext(ctx, updateIsPostedValueOfPost,
-		arg.IsPosted,
-		arg.Position,
-		arg.PostedAt,
+func (q *Queries) UpdatePostCaption(ctx context.Context, arg UpdatePostCaptionParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostCaption, arg.Caption, arg.ID)
+	return err
+}
+
+const updatePostCommentStatus = `-- name: UpdatePostCommentStatus :exec
+UPDATE
+    posts
+SET
+    comment_status = ?,
+    comment_error = ?
+WHERE
+    id = ?
+`
+
+type UpdatePostCommentStatusParams struct {
+	CommentStatus string
+	CommentError  string
+	ID            int64
+}
+
+func (q *Queries) UpdatePostCommentStatus(ctx context.Context, arg UpdatePostCommentStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostCommentStatus, arg.CommentStatus, arg.CommentError, arg.ID)
+	return err
+}
+
+const updatePostFiles = `-- name: UpdatePostFiles :execrows
+UPDATE
+    posts
+SET
+    image_filenames = ?,
+    photo_count = ?,
+    post_type = ?
+WHERE
+    id = ?
+    AND status IN ('draft', 'queued', 'failed')
+`
+
+type UpdatePostFilesParams struct {
+	ImageFilenames string
+	PhotoCount     int64
+	PostType       string
+	ID             int64
+}
+
+func (q *Queries) UpdatePostFiles(ctx context.Context, arg UpdatePostFilesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updatePostFiles,
+		arg.ImageFilenames,
+		arg.PhotoCount,
+		arg.PostType,
 		arg.ID,
 	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updatePostFirstComment = `-- name: UpdatePostFirstComment :exec
+UPDATE
+    posts
+SET
+    first_comment = ?
+WHERE
+    id = ?
+`
+
+type UpdatePostFirstCommentParams struct {
+	FirstComment string
+	ID           int64
+}
+
+func (q *Queries) UpdatePostFirstComment(ctx context.Context, arg UpdatePostFirstCommentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostFirstComment, arg.FirstComment, arg.ID)
 	return err
 }
 
-const updatePostCaption = `-- name: UpdatePostCaption :exec
+const updatePostLocation = `-- name: UpdatePostLocation :exec
 UPDATE
     posts
 SET
-    caption = ?
+    location_name = ?,
+    location_lat = ?,
+    location_lng = ?
 WHERE
     id = ?
 `
 
-type UpdatePostCaptionParams struct {
-	Caption string
-	ID      int64
+type UpdatePostLocationParams struct {
+	LocationName string
+	LocationLat  sql.NullFloat64
+	LocationLng  sql.NullFloat64
+	ID           int64
 }
 
-func (q *Queries) UpdatePostCaption(ctx context.Context, arg UpdatePostCaptionParams) error {
-	_, err := q.db.ExecContext(ctx, updatePostCaption, arg.Caption, arg.ID)
+func (q *Queries) UpdatePostLocation(ctx context.Context, arg UpdatePostLocationParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostLocation,
+		arg.LocationName,
+		arg.LocationLat,
+		arg.LocationLng,
+		arg.ID,
+	)
+	return err
+}
+
+const updatePostMediaID = `-- name: UpdatePostMediaID :exec
+UPDATE
+    posts
+SET
+    media_id = ?,
+    comment_status = ?,
+    comment_error = ''
+WHERE
+    id = ?
+`
+
+type UpdatePostMediaIDParams struct {
+	MediaID       string
+	CommentStatus string
+	ID            int64
+}
+
+func (q *Queries) UpdatePostMediaID(ctx context.Context, arg UpdatePostMediaIDParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostMediaID, arg.MediaID, arg.CommentStatus, arg.ID)
 	return err
 }
 
@@ -310,3 +756,59 @@ func (q *Queries) UpdatePostPosition(ctx context.Context, arg UpdatePostPosition
 	_, err := q.db.ExecContext(ctx, updatePostPosition, arg.Position, arg.ID)
 	return err
 }
+
+const updatePostSchedule = `-- name: UpdatePostSchedule :exec
+UPDATE
+    posts
+SET
+    scheduled_at = ?,
+    position = ?
+WHERE
+    id = ?
+`
+
+type UpdatePostScheduleParams struct {
+	ScheduledAt sql.NullString
+	Position    int64
+	ID          int64
+}
+
+func (q *Queries) UpdatePostSchedule(ctx context.Context, arg UpdatePostScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostSchedule, arg.ScheduledAt, arg.Position, arg.ID)
+	return err
+}
+
+const updatePostStatus = `-- name: UpdatePostStatus :execrows
+UPDATE
+    posts
+SET
+    status = ?,
+    position = ?,
+    posted_at = ?,
+    lease_expires_at = NULL
+WHERE
+    id = ?
+    AND status = ?
+`
+
+type UpdatePostStatusParams struct {
+	Status     string
+	Position   int64
+	PostedAt   sql.NullString
+	ID         int64
+	FromStatus string
+}
+
+func (q *Queries) UpdatePostStatus(ctx context.Context, arg UpdatePostStatusParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updatePostStatus,
+		arg.Status,
+		arg.Position,
+		arg.PostedAt,
+		arg.ID,
+		arg.FromStatus,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,40 @@
+-- name: InsertBlackout :one
+INSERT INTO
+    blackouts (name, starts_at, ends_at, recurrence, timestamp)
+VALUES
+    (?, ?, ?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetAllBlackouts :many
+SELECT
+    *
+FROM
+    blackouts
+ORDER BY
+    starts_at ASC;
+
+-- name: GetBlackoutById :one
+SELECT
+    *
+FROM
+    blackouts
+WHERE
+    id = ?;
+
+-- name: UpdateBlackout :execrows
+UPDATE
+    blackouts
+SET
+    name = ?,
+    starts_at = ?,
+    ends_at = ?,
+    recurrence = ?
+WHERE
+    id = ?;
+
+-- name: DeleteBlackout :execrows
+DELETE FROM
+    blackouts
+WHERE
+    id = ?;
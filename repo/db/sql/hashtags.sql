@@ -0,0 +1,116 @@
+-- name: InsertHashtagSet :one
+INSERT INTO
+    hashtag_sets (name, hashtags, timestamp)
+VALUES
+    (?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetAllHashtagSets :many
+SELECT
+    *
+FROM
+    hashtag_sets
+ORDER BY
+    name ASC;
+
+-- name: GetHashtagSetById :one
+SELECT
+    *
+FROM
+    hashtag_sets
+WHERE
+    id = ?;
+
+-- name: GetHashtagSetByName :one
+SELECT
+    *
+FROM
+    hashtag_sets
+WHERE
+    name = ?;
+
+-- name: UpdateHashtagSet :exec
+UPDATE
+    hashtag_sets
+SET
+    name = ?,
+    hashtags = ?
+WHERE
+    id = ?;
+
+-- name: DeleteHashtagSet :exec
+DELETE FROM
+    hashtag_sets
+WHERE
+    id = ?;
+
+-- name: GetHashtagSetsForPost :many
+SELECT
+    hashtag_sets.*
+FROM
+    hashtag_sets
+JOIN
+    post_hashtag_sets ON post_hashtag_sets.hashtag_set_id = hashtag_sets.id
+WHERE
+    post_hashtag_sets.post_id = ?
+ORDER BY
+    hashtag_sets.name ASC;
+
+-- name: InsertPostHashtagSet :exec
+INSERT INTO
+    post_hashtag_sets (post_id, hashtag_set_id)
+VALUES
+    (?, ?);
+
+-- name: DeletePostHashtagSetsForPost :exec
+DELETE FROM
+    post_hashtag_sets
+WHERE
+    post_id = ?;
+
+-- name: DeletePostHashtagSetsForHashtagSet :exec
+DELETE FROM
+    post_hashtag_sets
+WHERE
+    hashtag_set_id = ?;
+
+-- name: InsertHashtagUsage :exec
+INSERT OR IGNORE INTO
+    hashtag_usage (post_id, hashtag)
+VALUES
+    (?, ?);
+
+-- name: DeleteHashtagUsageForPost :exec
+DELETE FROM
+    hashtag_usage
+WHERE
+    post_id = ?;
+
+-- name: GetHashtagUsage :many
+SELECT
+    hashtag,
+    COUNT(*) AS count
+FROM
+    hashtag_usage
+GROUP BY
+    hashtag
+ORDER BY
+    count DESC,
+    hashtag ASC;
+
+-- name: GetPostedPostsWithoutHashtagUsage :many
+SELECT
+    *
+FROM
+    posts
+WHERE
+    status = 'posted'
+    AND id NOT IN (
+        SELECT
+            post_id
+        FROM
+            hashtag_usage
+    )
+ORDER BY
+    id ASC;
@@ -0,0 +1,36 @@
+-- name: InsertIdempotencyKey :execrows
+INSERT INTO
+    idempotency_keys (key, fingerprint, expires_at, timestamp)
+VALUES
+    (?, ?, ?, ?)
+ON CONFLICT (key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT
+    *
+FROM
+    idempotency_keys
+WHERE
+    key = ?;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE
+    idempotency_keys
+SET
+    status_code = ?,
+    header = ?,
+    body = ?
+WHERE
+    key = ?;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM
+    idempotency_keys
+WHERE
+    key = ?;
+
+-- name: DeleteExpiredIdempotencyKeys :exec
+DELETE FROM
+    idempotency_keys
+WHERE
+    expires_at <= ?;
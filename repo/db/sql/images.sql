@@ -0,0 +1,55 @@
+-- name: GetPostImages :many
+SELECT
+    *
+FROM
+    post_images
+WHERE
+    post_id = ?;
+
+-- name: InsertPostImage :exec
+INSERT INTO
+    post_images (post_id, filename, alt_text)
+VALUES
+    (?, ?, ?);
+
+-- name: DeletePostImages :exec
+DELETE FROM
+    post_images
+WHERE
+    post_id = ?;
+
+-- name: GetPostUserTags :many
+SELECT
+    *
+FROM
+    post_user_tags
+WHERE
+    post_id = ?
+ORDER BY
+    id ASC;
+
+-- name: InsertPostUserTag :exec
+INSERT INTO
+    post_user_tags (post_id, filename, username, x, y)
+VALUES
+    (?, ?, ?, ?, ?);
+
+-- name: DeletePostUserTags :exec
+DELETE FROM
+    post_user_tags
+WHERE
+    post_id = ?;
+
+-- name: DeletePostImage :exec
+DELETE FROM
+    post_images
+WHERE
+    post_id = ?
+    AND filename = ?;
+
+-- name: DeletePostUserTagsForFile :exec
+DELETE FROM
+    post_user_tags
+WHERE
+    post_id = ?
+    AND filename = ?;
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS posts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    image_filenames TEXT NOT NULL,
+    caption TEXT NOT NULL,
+    timestamp TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    photo_count INTEGER NOT NULL,
+    is_posted INTEGER NOT NULL,
+    posted_at TEXT DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS hashtag_sets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    hashtags TEXT NOT NULL,
+    timestamp TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS post_hashtag_sets (
+    post_id INTEGER NOT NULL,
+    hashtag_set_id INTEGER NOT NULL,
+    PRIMARY KEY (post_id, hashtag_set_id)
+);
+
+CREATE TABLE IF NOT EXISTS hashtag_usage (
+    post_id INTEGER NOT NULL,
+    hashtag TEXT NOT NULL,
+    PRIMARY KEY (post_id, hashtag)
+);
+
+CREATE TABLE IF NOT EXISTS caption_variables (
+    account TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (account, key)
+);
@@ -0,0 +1,17 @@
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'queued';
+
+UPDATE
+    posts
+SET
+    status = 'posted'
+WHERE
+    is_posted = 1;
+
+UPDATE
+    posts
+SET
+    position = 0
+WHERE
+    is_posted = 1;
+
+ALTER TABLE posts DROP COLUMN is_posted;
@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN scheduled_at TEXT DEFAULT NULL;
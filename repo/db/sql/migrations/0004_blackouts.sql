@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS blackouts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    starts_at TEXT NOT NULL,
+    ends_at TEXT NOT NULL,
+    recurrence TEXT NOT NULL DEFAULT 'none',
+    timestamp TEXT NOT NULL
+);
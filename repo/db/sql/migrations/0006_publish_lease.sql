@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN lease_expires_at TEXT DEFAULT NULL;
@@ -0,0 +1,10 @@
+ALTER TABLE posts ADD COLUMN post_type TEXT NOT NULL DEFAULT 'feed';
+
+ALTER TABLE posts ADD COLUMN cover_filename TEXT NOT NULL DEFAULT '';
+
+UPDATE
+    posts
+SET
+    post_type = 'carousel'
+WHERE
+    photo_count > 1;
@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN queue TEXT NOT NULL DEFAULT 'feed';
@@ -0,0 +1,21 @@
+ALTER TABLE posts ADD COLUMN location_name TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE posts ADD COLUMN location_lat REAL;
+
+ALTER TABLE posts ADD COLUMN location_lng REAL;
+
+CREATE TABLE IF NOT EXISTS post_images (
+    post_id INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    alt_text TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (post_id, filename)
+);
+
+CREATE TABLE IF NOT EXISTS post_user_tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    username TEXT NOT NULL,
+    x REAL NOT NULL,
+    y REAL NOT NULL
+);
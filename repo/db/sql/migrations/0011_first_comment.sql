@@ -0,0 +1,7 @@
+ALTER TABLE posts ADD COLUMN first_comment TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE posts ADD COLUMN media_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE posts ADD COLUMN comment_status TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE posts ADD COLUMN comment_error TEXT NOT NULL DEFAULT '';
@@ -1,8 +1,8 @@
 -- name: InsertPost :one
 INSERT INTO
-    posts (image_filenames, caption, timestamp, position, photo_count, is_posted)
+    posts (image_filenames, caption, timestamp, position, photo_count, status, post_type, cover_filename, queue, first_comment)
 VALUES
-    (?, ?, ?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING
     *;
 
@@ -32,7 +32,9 @@ SELECT
 FROM
     posts
 WHERE
-    is_posted = 0
+    status = 'queued'
+    AND scheduled_at IS NULL
+    AND queue = ?
 ORDER BY
     position DESC
 LIMIT
@@ -62,17 +64,21 @@ FROM
 WHERE
     position = ?
 AND
-    is_posted = 0;
+    queue = ?
+AND
+    status = 'queued';
 
--- name: UpdateIsPostedValueOfPost :exec
+-- name: UpdatePostStatus :execrows
 UPDATE
     posts
 SET
-    is_posted = ?,
+    status = ?,
     position = ?,
-    posted_at = ?
+    posted_at = ?,
+    lease_expires_at = NULL
 WHERE
-    id = ?;
+    id = ?
+    AND status = sqlc.arg(from_status);
 
 -- name: GetPostToPost :one
 SELECT
@@ -80,8 +86,10 @@ SELECT
 FROM
     posts
 WHERE
-    is_posted = 0
+    status = 'queued'
+    AND scheduled_at IS NULL
     AND position = 1
+    AND queue = ?
 LIMIT
     1;
 
@@ -91,6 +99,133 @@ SELECT
 FROM
     posts
 WHERE
-    is_posted = 0
+    status = 'queued'
+    AND scheduled_at IS NULL
+    AND queue = ?
 ORDER BY
     position ASC;
+
+-- name: CountPostedPosts :one
+SELECT
+    COUNT(*)
+FROM
+    posts
+WHERE
+    status = 'posted';
+
+-- name: GetPostsByStatus :many
+SELECT
+    *
+FROM
+    posts
+WHERE
+    status = ?
+ORDER BY
+    position ASC,
+    id ASC;
+
+-- name: UpdatePostSchedule :exec
+UPDATE
+    posts
+SET
+    scheduled_at = ?,
+    position = ?
+WHERE
+    id = ?;
+
+-- name: GetPinnedPosts :many
+SELECT
+    *
+FROM
+    posts
+WHERE
+    status = 'queued'
+    AND scheduled_at IS NOT NULL
+    AND queue = ?
+ORDER BY
+    scheduled_at ASC;
+
+-- name: GetDuePinnedPost :one
+SELECT
+    *
+FROM
+    posts
+WHERE
+    status = 'queued'
+    AND scheduled_at IS NOT NULL
+    AND scheduled_at <= ?
+    AND queue = ?
+ORDER BY
+    scheduled_at ASC
+LIMIT
+    1;
+
+-- name: ClaimPost :execrows
+UPDATE
+    posts
+SET
+    status = 'publishing',
+    position = 0,
+    lease_expires_at = ?
+WHERE
+    id = ?
+    AND status = 'queued';
+
+-- name: GetExpiredLeases :many
+SELECT
+    *
+FROM
+    posts
+WHERE
+    status = 'publishing'
+    AND lease_expires_at <= ?
+ORDER BY
+    id ASC;
+
+-- name: UpdatePostLocation :exec
+UPDATE
+    posts
+SET
+    location_name = ?,
+    location_lat = ?,
+    location_lng = ?
+WHERE
+    id = ?;
+
+-- name: UpdatePostFirstComment :exec
+UPDATE
+    posts
+SET
+    first_comment = ?
+WHERE
+    id = ?;
+
+-- name: UpdatePostMediaID :exec
+UPDATE
+    posts
+SET
+    media_id = ?,
+    comment_status = ?,
+    comment_error = ''
+WHERE
+    id = ?;
+
+-- name: UpdatePostCommentStatus :exec
+UPDATE
+    posts
+SET
+    comment_status = ?,
+    comment_error = ?
+WHERE
+    id = ?;
+
+-- name: UpdatePostFiles :execrows
+UPDATE
+    posts
+SET
+    image_filenames = ?,
+    photo_count = ?,
+    post_type = ?
+WHERE
+    id = ?
+    AND status IN ('draft', 'queued', 'failed');
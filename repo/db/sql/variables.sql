@@ -0,0 +1,24 @@
+-- name: GetCaptionVariables :many
+SELECT
+    *
+FROM
+    caption_variables
+WHERE
+    account = ?
+ORDER BY
+    key ASC;
+
+-- name: UpsertCaptionVariable :exec
+INSERT INTO
+    caption_variables (account, key, value)
+VALUES
+    (?, ?, ?)
+ON CONFLICT (account, key) DO UPDATE SET
+    value = excluded.value;
+
+-- name: DeleteCaptionVariable :execrows
+DELETE FROM
+    caption_variables
+WHERE
+    account = ?
+    AND key = ?;
@@ -0,0 +1,88 @@
+-- name: InsertWebhook :one
+INSERT INTO
+    webhooks (url, secret, events, timestamp)
+VALUES
+    (?, ?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetAllWebhooks :many
+SELECT
+    *
+FROM
+    webhooks
+ORDER BY
+    id ASC;
+
+-- name: GetWebhookById :one
+SELECT
+    *
+FROM
+    webhooks
+WHERE
+    id = ?;
+
+-- name: UpdateWebhook :execrows
+UPDATE
+    webhooks
+SET
+    url = ?,
+    secret = ?,
+    events = ?
+WHERE
+    id = ?;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM
+    webhooks
+WHERE
+    id = ?;
+
+-- name: InsertWebhookDelivery :one
+INSERT INTO
+    webhook_deliveries (webhook_id, event, payload, next_attempt_at, timestamp)
+VALUES
+    (?, ?, ?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetDueWebhookDeliveries :many
+SELECT
+    *
+FROM
+    webhook_deliveries
+WHERE
+    status = 'pending'
+    AND next_attempt_at <= ?
+ORDER BY
+    next_attempt_at ASC;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE
+    webhook_deliveries
+SET
+    status = ?,
+    attempts = ?,
+    response_code = ?,
+    error = ?,
+    next_attempt_at = ?
+WHERE
+    id = ?;
+
+-- name: GetWebhookDeliveries :many
+SELECT
+    *
+FROM
+    webhook_deliveries
+WHERE
+    webhook_id = ?
+ORDER BY
+    id DESC
+LIMIT
+    ?;
+
+-- name: DeleteWebhookDeliveriesForWebhook :exec
+DELETE FROM
+    webhook_deliveries
+WHERE
+    webhook_id = ?;
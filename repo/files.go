@@ -0,0 +1,248 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/btschwartz12/isza/repo/db"
+)
+
+var ErrPostNotEditable = fmt.Errorf("only draft, queued and failed posts can have their files changed")
+
+// FilesEditable reports whether files can still be added to, removed from
+// or reordered in the post.
+func (p *Post) FilesEditable() bool {
+	return p.Status == StatusDraft || p.Status == StatusQueued || p.Status == StatusFailed
+}
+
+// editedType returns the type of a post of type t once it has n files. Feed
+// posts and carousels turn into each other as files come and go; other
+// types keep their type.
+func editedType(t PostType, n int) PostType {
+	if t != TypeFeed && t != TypeCarousel {
+		return t
+	}
+	if n > 1 {
+		return TypeCarousel
+	}
+	return TypeFeed
+}
+
+func countVideos(filenames []string) int {
+	videos := 0
+	for _, filename := range filenames {
+		if IsVideoFile(filename) {
+			videos++
+		}
+	}
+	return videos
+}
+
+// AddPostFiles appends files to a post, after its existing files, with
+// their alt text. A feed post becomes a carousel. It returns an error
+// wrapping ErrInvalidMedia if the files do not suit the post,
+// ErrInvalidMetadata if an alt text is too long, ErrMissingAltText if the
+// post is queued and would have a photo that needs alt text and has none, or
+// ErrPostNotEditable if the post has been published.
+func (r *Repo) AddPostFiles(ctx context.Context, id int64, files []UploadFile) (*Post, error) {
+	if r.storageFull() {
+		return nil, ErrStorageFull
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: no files uploaded", ErrInvalidMedia)
+	}
+	post, err := r.GetPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !post.FilesEditable() {
+		return nil, fmt.Errorf("%w: post %d is %s", ErrPostNotEditable, id, post.Status)
+	}
+	uploadNames := make([]string, len(files))
+	for i, file := range files {
+		if !allowedExtensionsRe.MatchString(filepath.Ext(file.Header.Filename)) {
+			return nil, ErrInvalidExtension
+		}
+		uploadNames[i] = file.Header.Filename
+	}
+	n := len(post.ImageFilenames) + len(files)
+	t := editedType(post.Type, n)
+	videos := countVideos(post.ImageFilenames) + countVideos(uploadNames)
+	if err := checkCount(t, n, videos, r.maxCarouselItems); err != nil {
+		return nil, err
+	}
+	if err := checkFiles(t, files, len(post.ImageFilenames)+1); err != nil {
+		return nil, err
+	}
+	if err := r.checkAddedAltText(ctx, post, t, files); err != nil {
+		return nil, err
+	}
+
+	newNames := make([]string, 0, len(files))
+	for _, file := range files {
+		newName, err := r.saveUpload(file)
+		if err != nil {
+			r.removeUploads(newNames)
+			return nil, err
+		}
+		newNames = append(newNames, newName)
+	}
+	filenames := append(slices.Clone(post.ImageFilenames), newNames...)
+	added := make([]Image, len(files))
+	for i, file := range files {
+		added[i] = Image{Filename: newNames[i], AltText: file.AltText}
+	}
+	if err := r.setPostFiles(ctx, post, t, filenames, added, ""); err != nil {
+		r.removeUploads(newNames)
+		return nil, err
+	}
+	return r.GetPost(ctx, id)
+}
+
+// RemovePostFile removes a file from a post and deletes it, along with its
+// alt text and user tags. A carousel left with one file becomes a feed post.
+// It returns an error wrapping ErrInvalidMedia if the post has no such file
+// or would be left without the files its type needs, or ErrPostNotEditable
+// if the post has been published.
+func (r *Repo) RemovePostFile(ctx context.Context, id int64, filename string) (*Post, error) {
+	post, err := r.GetPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !post.FilesEditable() {
+		return nil, fmt.Errorf("%w: post %d is %s", ErrPostNotEditable, id, post.Status)
+	}
+	i := slices.Index(post.ImageFilenames, filename)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: post %d has no file %q", ErrInvalidMedia, id, filename)
+	}
+	filenames := slices.Delete(slices.Clone(post.ImageFilenames), i, i+1)
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("%w: a post needs at least one file", ErrInvalidMedia)
+	}
+	t := editedType(post.Type, len(filenames))
+	if err := checkCount(t, len(filenames), countVideos(filenames), r.maxCarouselItems); err != nil {
+		return nil, err
+	}
+	if err := r.setPostFiles(ctx, post, t, filenames, nil, filename); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(filepath.Join(r.varDir, postUploadDir, filename)); err != nil {
+		return nil, fmt.Errorf("error deleting file: %w", err)
+	}
+	return r.GetPost(ctx, id)
+}
+
+// ReorderPostFiles puts the files of a post in the order of filenames, which
+// must list each of them once. It returns an error wrapping ErrInvalidMedia
+// if it does not, or ErrPostNotEditable if the post has been published.
+func (r *Repo) ReorderPostFiles(ctx context.Context, id int64, filenames []string) (*Post, error) {
+	post, err := r.GetPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !post.FilesEditable() {
+		return nil, fmt.Errorf("%w: post %d is %s", ErrPostNotEditable, id, post.Status)
+	}
+	sorted, current := slices.Clone(filenames), slices.Clone(post.ImageFilenames)
+	slices.Sort(sorted)
+	slices.Sort(current)
+	if !slices.Equal(sorted, current) {
+		return nil, fmt.Errorf("%w: the new order must list each file of post %d once", ErrInvalidMedia, id)
+	}
+	if err := r.setPostFiles(ctx, post, post.Type, filenames, nil, ""); err != nil {
+		return nil, err
+	}
+	return r.GetPost(ctx, id)
+}
+
+// setPostFiles saves the files and type of a post, with the alt text of the
+// added files, dropping the metadata of removed, a file no longer in it,
+// unless removed is empty. The update only applies while the post's files
+// are editable, so it cannot race a publish.
+func (r *Repo) setPostFiles(ctx context.Context, post *Post, t PostType, filenames []string, added []Image, removed string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := db.New(r.db).WithTx(tx)
+	n, err := q.UpdatePostFiles(ctx, db.UpdatePostFilesParams{
+		ImageFilenames: strings.Join(filenames, ","),
+		PhotoCount:     int64(len(filenames)),
+		PostType:       string(t),
+		ID:             post.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating post files: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: post %d is no longer editable", ErrPostNotEditable, post.ID)
+	}
+	for _, image := range added {
+		if strings.TrimSpace(image.AltText) == "" {
+			continue
+		}
+		err := q.InsertPostImage(ctx, db.InsertPostImageParams{
+			PostID:   post.ID,
+			Filename: image.Filename,
+			AltText:  strings.TrimSpace(image.AltText),
+		})
+		if err != nil {
+			return fmt.Errorf("error inserting post image: %w", err)
+		}
+	}
+	if removed != "" {
+		err := q.DeletePostImage(ctx, db.DeletePostImageParams{PostID: post.ID, Filename: removed})
+		if err != nil {
+			return fmt.Errorf("error deleting post image: %w", err)
+		}
+		err = q.DeletePostUserTagsForFile(ctx, db.DeletePostUserTagsForFileParams{PostID: post.ID, Filename: removed})
+		if err != nil {
+			return fmt.Errorf("error deleting post user tags: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// checkAddedAltText checks the alt text of files added to post, which then
+// has type t, as InsertPost checks that of a new post: each must fit, and a
+// queued post must be left with alt text on every photo when it is
+// required.
+func (r *Repo) checkAddedAltText(ctx context.Context, post *Post, t PostType, files []UploadFile) error {
+	for _, file := range files {
+		if err := checkImage(Image{Filename: file.Header.Filename, AltText: file.AltText}); err != nil {
+			return err
+		}
+	}
+	if post.Status != StatusQueued || !r.requireAltText {
+		return nil
+	}
+	images, err := r.GetPostImages(ctx, post.ID)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		images = append(images, Image{Filename: file.Header.Filename, AltText: file.AltText})
+	}
+	for i, image := range images {
+		if NeedsAltText(t, image.Filename) && strings.TrimSpace(image.AltText) == "" {
+			return fmt.Errorf("%w: file %d of post %d is a photo without alt text", ErrMissingAltText, i+1, post.ID)
+		}
+	}
+	return nil
+}
+
+// removeUploads deletes files saved for a change that did not go through.
+func (r *Repo) removeUploads(filenames []string) {
+	for _, filename := range filenames {
+		os.Remove(filepath.Join(r.varDir, postUploadDir, filename))
+	}
+}
@@ -0,0 +1,281 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/isza/caption"
+	"github.com/btschwartz12/isza/repo/db"
+)
+
+var (
+	ErrHashtagSetNotFound = fmt.Errorf("hashtag set not found")
+	ErrHashtagSetExists   = fmt.Errorf("hashtag set already exists")
+	ErrInvalidHashtagSet  = fmt.Errorf("invalid hashtag set")
+)
+
+type HashtagSet struct {
+	ID        int64
+	Name      string
+	Hashtags  []string
+	Timestamp time.Time
+}
+
+func (h *HashtagSet) fromDb(row *db.HashtagSet) {
+	h.ID = row.ID
+	h.Name = row.Name
+	h.Hashtags = strings.Split(row.Hashtags, ",")
+	t, _ := time.Parse(time.RFC3339, row.Timestamp)
+	h.Timestamp = t
+}
+
+// In returns the hashtag set with its timestamp in loc.
+func (h HashtagSet) In(loc *time.Location) HashtagSet {
+	h.Timestamp = h.Timestamp.In(loc)
+	return h
+}
+
+type HashtagUsage struct {
+	Hashtag string
+	Count   int64
+}
+
+func (r *Repo) InsertHashtagSet(ctx context.Context, name string, hashtags []string) (*HashtagSet, error) {
+	if err := validateHashtagSet(name, hashtags); err != nil {
+		return nil, err
+	}
+	q := db.New(r.db)
+	if _, err := q.GetHashtagSetByName(ctx, name); err == nil {
+		return nil, ErrHashtagSetExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("error checking for existing hashtag set: %w", err)
+	}
+	row, err := q.InsertHashtagSet(ctx, db.InsertHashtagSetParams{
+		Name:      name,
+		Hashtags:  strings.Join(hashtags, ","),
+		Timestamp: zulu(time.Now()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error inserting hashtag set: %w", err)
+	}
+	set := &HashtagSet{}
+	set.fromDb(&row)
+	return set, nil
+}
+
+func (r *Repo) GetAllHashtagSets(ctx context.Context) ([]HashtagSet, error) {
+	q := db.New(r.db)
+	rows, err := q.GetAllHashtagSets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting all hashtag sets: %w", err)
+	}
+	sets := make([]HashtagSet, len(rows))
+	for i, row := range rows {
+		sets[i].fromDb(&row)
+	}
+	return sets, nil
+}
+
+func (r *Repo) GetHashtagSet(ctx context.Context, id int64) (*HashtagSet, error) {
+	q := db.New(r.db)
+	row, err := q.GetHashtagSetById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrHashtagSetNotFound
+		}
+		return nil, fmt.Errorf("error getting hashtag set: %w", err)
+	}
+	set := &HashtagSet{}
+	set.fromDb(&row)
+	return set, nil
+}
+
+func (r *Repo) UpdateHashtagSet(ctx context.Context, id int64, name string, hashtags []string) error {
+	if err := validateHashtagSet(name, hashtags); err != nil {
+		return err
+	}
+	if _, err := r.GetHashtagSet(ctx, id); err != nil {
+		return err
+	}
+	q := db.New(r.db)
+	existing, err := q.GetHashtagSetByName(ctx, name)
+	if err == nil && existing.ID != id {
+		return ErrHashtagSetExists
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error checking for existing hashtag set: %w", err)
+	}
+	err = q.UpdateHashtagSet(ctx, db.UpdateHashtagSetParams{
+		Name:     name,
+		Hashtags: strings.Join(hashtags, ","),
+		ID:       id,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating hashtag set: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) DeleteHashtagSet(ctx context.Context, id int64) error {
+	if _, err := r.GetHashtagSet(ctx, id); err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := db.New(r.db).WithTx(tx)
+	if err := q.DeletePostHashtagSetsForHashtagSet(ctx, id); err != nil {
+		return fmt.Errorf("error unlinking hashtag set from posts: %w", err)
+	}
+	if err := q.DeleteHashtagSet(ctx, id); err != nil {
+		return fmt.Errorf("error deleting hashtag set: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// GetHashtagSets returns the hashtag sets with the given IDs, in the order
+// they are appended to a caption. It returns ErrHashtagSetNotFound if one does
+// not exist.
+func (r *Repo) GetHashtagSets(ctx context.Context, ids []int64) ([]HashtagSet, error) {
+	ids = uniqueIDs(ids)
+	sets := make([]HashtagSet, 0, len(ids))
+	for _, id := range ids {
+		set, err := r.GetHashtagSet(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, *set)
+	}
+	slices.SortFunc(sets, func(a, b HashtagSet) int { return strings.Compare(a.Name, b.Name) })
+	return sets, nil
+}
+
+func (r *Repo) GetHashtagSetsForPost(ctx context.Context, postID int64) ([]HashtagSet, error) {
+	q := db.New(r.db)
+	rows, err := q.GetHashtagSetsForPost(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting hashtag sets for post: %w", err)
+	}
+	sets := make([]HashtagSet, len(rows))
+	for i, row := range rows {
+		sets[i].fromDb(&row)
+	}
+	return sets, nil
+}
+
+// SetPostHashtagSets replaces the hashtag sets that are appended to a post's
+// caption when it is published.
+func (r *Repo) SetPostHashtagSets(ctx context.Context, postID int64, setIDs []int64) error {
+	if _, err := r.GetPost(ctx, postID); err != nil {
+		return err
+	}
+	// A set can only be linked to a post once.
+	setIDs = uniqueIDs(setIDs)
+	for _, id := range setIDs {
+		if _, err := r.GetHashtagSet(ctx, id); err != nil {
+			return err
+		}
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := db.New(r.db).WithTx(tx)
+	if err := q.DeletePostHashtagSetsForPost(ctx, postID); err != nil {
+		return fmt.Errorf("error clearing post hashtag sets: %w", err)
+	}
+	for _, id := range setIDs {
+		err := q.InsertPostHashtagSet(ctx, db.InsertPostHashtagSetParams{
+			PostID:       postID,
+			HashtagSetID: id,
+		})
+		if err != nil {
+			return fmt.Errorf("error linking hashtag set to post: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// RecordHashtagUsage stores the hashtags that went out with a published post.
+func (r *Repo) RecordHashtagUsage(ctx context.Context, postID int64, hashtags []string) error {
+	q := db.New(r.db)
+	for _, tag := range hashtags {
+		err := q.InsertHashtagUsage(ctx, db.InsertHashtagUsageParams{
+			PostID:  postID,
+			Hashtag: strings.ToLower(tag),
+		})
+		if err != nil {
+			return fmt.Errorf("error recording hashtag usage: %w", err)
+		}
+	}
+	return nil
+}
+
+// backfillHashtagUsage records the hashtags of posted posts that have no
+// usage recorded, such as those published before isza kept track of it. Their
+// caption variables are not rendered, so only the hashtags written in the
+// caption itself and those of its hashtag sets are counted.
+func (r *Repo) backfillHashtagUsage(ctx context.Context) error {
+	q := db.New(r.db)
+	rows, err := q.GetPostedPostsWithoutHashtagUsage(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting posts without hashtag usage: %w", err)
+	}
+	for _, row := range rows {
+		sets, err := r.GetHashtagSetsForPost(ctx, row.ID)
+		if err != nil {
+			return err
+		}
+		text := AppendHashtagSets(row.Caption, sets)
+		if err := r.RecordHashtagUsage(ctx, row.ID, caption.Lint(text).Hashtags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repo) GetHashtagUsage(ctx context.Context) ([]HashtagUsage, error) {
+	q := db.New(r.db)
+	rows, err := q.GetHashtagUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting hashtag usage: %w", err)
+	}
+	usage := make([]HashtagUsage, len(rows))
+	for i, row := range rows {
+		usage[i] = HashtagUsage{
+			Hashtag: row.Hashtag,
+			Count:   row.Count,
+		}
+	}
+	return usage, nil
+}
+
+// uniqueIDs returns a sorted copy of ids without repeats.
+func uniqueIDs(ids []int64) []int64 {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+func validateHashtagSet(name string, hashtags []string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidHashtagSet)
+	}
+	if len(hashtags) == 0 {
+		return fmt.Errorf("%w: at least one hashtag is required", ErrInvalidHashtagSet)
+	}
+	return nil
+}
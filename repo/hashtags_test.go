@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/repo/db"
+)
+
+func TestBackfillHashtagUsage(t *testing.T) {
+	ctx := context.Background()
+	varDir := t.TempDir()
+	newRepo := func() *Repo {
+		r, err := NewRepo(zap.NewNop().Sugar(), varDir, time.UTC, Options{MaxCarouselItems: MaxCarouselItems})
+		if err != nil {
+			t.Fatalf("creating repo: %v", err)
+		}
+		return r
+	}
+
+	r := newRepo()
+	set, err := r.InsertHashtagSet(ctx, "travel", []string{"#travel", "#Sunset"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := db.New(r.db)
+	for i, p := range []struct {
+		caption string
+		status  PostStatus
+	}{
+		{"Beach day #sunset #Beach", StatusPosted},
+		{"Back home #beach", StatusPosted},
+		{"Not out yet #draft", StatusDraft},
+	} {
+		row, err := q.InsertPost(ctx, db.InsertPostParams{
+			ImageFilenames: "a.png",
+			Caption:        p.caption,
+			Timestamp:      time.Now().Format(time.RFC3339),
+			Position:       int64(i),
+			PhotoCount:     1,
+			Status:         string(p.status),
+			PostType:       string(TypeFeed),
+			Queue:          string(QueueFeed),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := r.SetPostHashtagSets(ctx, row.ID, []int64{set.ID}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Posts published before usage was recorded are counted on the next start.
+	r = newRepo()
+	usage, err := r.GetHashtagUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HashtagUsage{{"#beach", 2}, {"#sunset", 1}, {"#travel", 1}}
+	if !slices.Equal(usage, want) {
+		t.Errorf("usage = %v, want %v", usage, want)
+	}
+
+	// Posts that already have usage are not counted twice.
+	r = newRepo()
+	if usage, err = r.GetHashtagUsage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(usage, want) {
+		t.Errorf("usage after restart = %v, want %v", usage, want)
+	}
+}
@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/btschwartz12/isza/repo/db"
+)
+
+// IdempotencyKey is a request made with an Idempotency-Key header and, once
+// it has finished, the response it got.
+type IdempotencyKey struct {
+	Key         string
+	Fingerprint string
+	// StatusCode is zero while the request is in progress.
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	ExpiresAt  time.Time
+	Timestamp  time.Time
+}
+
+func (k *IdempotencyKey) fromDb(row *db.IdempotencyKey) {
+	k.Key = row.Key
+	k.Fingerprint = row.Fingerprint
+	k.StatusCode = int(row.StatusCode)
+	k.Header = http.Header{}
+	if row.Header != "" {
+		json.Unmarshal([]byte(row.Header), &k.Header)
+	}
+	k.Body = row.Body
+	k.ExpiresAt, _ = time.Parse(time.RFC3339, row.ExpiresAt)
+	k.Timestamp, _ = time.Parse(time.RFC3339, row.Timestamp)
+}
+
+// Completed reports whether the response of the request has been stored.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
+
+// ReserveIdempotencyKey records key as in progress until expiresAt and
+// returns true, unless it is already taken, in which case it returns the
+// request that took it. Expired keys are deleted first, so they can be
+// reused.
+func (r *Repo) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, now, expiresAt time.Time) (*IdempotencyKey, bool, error) {
+	q := db.New(r.db)
+	if err := q.DeleteExpiredIdempotencyKeys(ctx, zulu(now)); err != nil {
+		return nil, false, fmt.Errorf("error deleting expired idempotency keys: %w", err)
+	}
+	n, err := q.InsertIdempotencyKey(ctx, db.InsertIdempotencyKeyParams{
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   zulu(expiresAt),
+		Timestamp:   zulu(now),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("error inserting idempotency key: %w", err)
+	}
+	if n == 1 {
+		return nil, true, nil
+	}
+	row, err := q.GetIdempotencyKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Released between the insert and the lookup; try again.
+			return r.ReserveIdempotencyKey(ctx, key, fingerprint, now, expiresAt)
+		}
+		return nil, false, fmt.Errorf("error getting idempotency key: %w", err)
+	}
+	existing := &IdempotencyKey{}
+	existing.fromDb(&row)
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request that reserved
+// key, to be replayed for repeats of it.
+func (r *Repo) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, header http.Header, body []byte) error {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("error marshalling response header: %w", err)
+	}
+	if body == nil {
+		// A nil slice would be stored as NULL.
+		body = []byte{}
+	}
+	q := db.New(r.db)
+	err = q.CompleteIdempotencyKey(ctx, db.CompleteIdempotencyKeyParams{
+		StatusCode: int64(statusCode),
+		Header:     string(h),
+		Body:       body,
+		Key:        key,
+	})
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes key, so that the request can be made again.
+func (r *Repo) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	q := db.New(r.db)
+	if err := q.DeleteIdempotencyKey(ctx, key); err != nil {
+		return fmt.Errorf("error deleting idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+
+	"github.com/btschwartz12/isza/media"
+)
+
+// PostType is how a post appears on Instagram, and so which upload call
+// publishes it.
+type PostType string
+
+const (
+	// TypeFeed is a single photo or video in the feed.
+	TypeFeed PostType = "feed"
+	// TypeCarousel is several photos or videos that are swiped through.
+	TypeCarousel PostType = "carousel"
+	// TypeReel is a single video, optionally with a cover image.
+	TypeReel PostType = "reel"
+	// TypeStory is a single 9:16 photo or video shown for a day. Stories have
+	// their own queue.
+	TypeStory PostType = "story"
+)
+
+// Queue is an ordered queue of posts with its own schedule. Each post is in
+// the queue of its type.
+type Queue string
+
+const (
+	QueueFeed  Queue = "feed"
+	QueueStory Queue = "story"
+)
+
+var (
+	imageExtensionsRe = regexp.MustCompile(`(?i)\.(jpe?g|png|gif)$`)
+	videoExtensionsRe = regexp.MustCompile(`(?i)\.(mp4|mov)$`)
+
+	ErrInvalidPostType = fmt.Errorf("invalid post type")
+	ErrInvalidMedia    = fmt.Errorf("invalid media")
+	ErrInvalidQueue    = fmt.Errorf("invalid queue")
+
+	AllPostTypes = []PostType{TypeFeed, TypeCarousel, TypeReel, TypeStory}
+	AllQueues    = []Queue{QueueFeed, QueueStory}
+)
+
+func ParsePostType(s string) (PostType, error) {
+	for _, t := range AllPostTypes {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidPostType, s)
+}
+
+func ParseQueue(s string) (Queue, error) {
+	for _, q := range AllQueues {
+		if string(q) == s {
+			return q, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidQueue, s)
+}
+
+// Queue returns the queue posts of type t wait in.
+func (t PostType) Queue() Queue {
+	if t == TypeStory {
+		return QueueStory
+	}
+	return QueueFeed
+}
+
+// IsVideoFile reports whether filename is a video, judging by its extension.
+func IsVideoFile(filename string) bool {
+	return videoExtensionsRe.MatchString(filepath.Ext(filename))
+}
+
+// Thumbnail returns the file that stands for the post in lists: its cover if
+// it has one, or else its first file.
+func (p *Post) Thumbnail() string {
+	if p.CoverFilename != "" {
+		return p.CoverFilename
+	}
+	return p.ImageFilenames[0]
+}
+
+// checkMedia validates the files of a new post of type t and returns the
+// type, inferred from the files if t is empty: several files make a
+// carousel, and a single video a reel. A carousel has at most maxItems
+// files. Videos are checked against the limits of the type, as are the
+// photos of stories.
+func checkMedia(t PostType, files []UploadFile, cover *UploadFile, maxItems int) (PostType, error) {
+	videos := 0
+	for _, file := range files {
+		if !allowedExtensionsRe.MatchString(filepath.Ext(file.Header.Filename)) {
+			return "", ErrInvalidExtension
+		}
+		if IsVideoFile(file.Header.Filename) {
+			videos++
+		}
+	}
+	if t == "" {
+		switch {
+		case len(files) > 1:
+			t = TypeCarousel
+		case videos == 1:
+			t = TypeReel
+		default:
+			t = TypeFeed
+		}
+	}
+
+	if err := checkCount(t, len(files), videos, maxItems); err != nil {
+		return "", err
+	}
+	if cover != nil {
+		if t != TypeReel {
+			return "", fmt.Errorf("%w: only reels have a cover image", ErrInvalidMedia)
+		}
+		if !imageExtensionsRe.MatchString(filepath.Ext(cover.Header.Filename)) {
+			return "", fmt.Errorf("%w: the cover must be a jpg, png or gif image", ErrInvalidMedia)
+		}
+	}
+
+	if err := checkFiles(t, files, 1); err != nil {
+		return "", err
+	}
+	return t, nil
+}
+
+// checkCount checks that n files, of which videos are videos, make a post of
+// type t, with at most maxItems files in a carousel.
+func checkCount(t PostType, n, videos, maxItems int) error {
+	switch t {
+	case TypeFeed:
+		if n != 1 {
+			return fmt.Errorf("%w: a feed post has exactly one file", ErrInvalidMedia)
+		}
+	case TypeCarousel:
+		if n < 2 {
+			return fmt.Errorf("%w: a carousel has at least two files", ErrInvalidMedia)
+		}
+		if n > maxItems {
+			return fmt.Errorf("%w: a carousel has at most %d files, not %d", ErrInvalidMedia, maxItems, n)
+		}
+	case TypeReel:
+		if n != 1 || videos != 1 {
+			return fmt.Errorf("%w: a reel is exactly one video", ErrInvalidMedia)
+		}
+	case TypeStory:
+		if n != 1 {
+			return fmt.Errorf("%w: a story has exactly one file", ErrInvalidMedia)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidPostType, t)
+	}
+	return nil
+}
+
+// checkFiles checks files against the limits of type t: videos always, and
+// photos too for stories. Files are numbered from first in errors.
+func checkFiles(t PostType, files []UploadFile, first int) error {
+	limits := media.FeedLimits
+	switch t {
+	case TypeReel:
+		limits = media.ReelLimits
+	case TypeStory:
+		limits = media.StoryLimits
+	}
+	for i, file := range files {
+		var err error
+		switch {
+		case IsVideoFile(file.Header.Filename):
+			var v *media.Video
+			if v, err = media.ProbeVideo(*file.File, file.Header.Size); err == nil {
+				err = limits.Check(v)
+			}
+		case t == TypeStory:
+			// Instagram crops story photos of any other shape.
+			var img *media.Image
+			if img, err = media.ProbeImage(io.NewSectionReader(*file.File, 0, file.Header.Size)); err == nil {
+				err = limits.CheckImage(img)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%w: file %d: %w", ErrInvalidMedia, first+i, err)
+		}
+	}
+	return nil
+}
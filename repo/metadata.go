@@ -0,0 +1,236 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/btschwartz12/isza/repo/db"
+	"github.com/samber/mo"
+)
+
+const (
+	// MaxAltTextLength is the longest alt text Instagram accepts.
+	MaxAltTextLength = 100
+	// MaxUserTags is the most users that can be tagged on one image.
+	MaxUserTags = 20
+)
+
+var (
+	usernameRe = regexp.MustCompile(`^[A-Za-z0-9._]{1,30}$`)
+
+	ErrInvalidMetadata = fmt.Errorf("invalid metadata")
+	ErrMissingAltText  = fmt.Errorf("missing alt text")
+)
+
+// UserTag tags an Instagram user at a point on an image. X and Y are
+// fractions of the width and height from the top left corner.
+type UserTag struct {
+	Username string
+	X        float64
+	Y        float64
+}
+
+// Image is the metadata of one file of a post.
+type Image struct {
+	Filename string
+	// AltText describes the image for screen readers. Instagram generates
+	// one if it is empty.
+	AltText  string
+	UserTags []UserTag
+}
+
+// Location is the place a post is tagged with.
+type Location struct {
+	Name string
+	Lat  float64
+	Lng  float64
+}
+
+// NeedsAltText reports whether a file of a post of type t needs alt text.
+// Instagram only shows alt text on photos in the feed, so videos and stories
+// go without.
+func NeedsAltText(t PostType, filename string) bool {
+	return t != TypeStory && !IsVideoFile(filename)
+}
+
+// MissingAltText reports whether any photo of a post of type t in images has
+// no alt text.
+func MissingAltText(t PostType, images []Image) bool {
+	for _, image := range images {
+		if NeedsAltText(t, image.Filename) && strings.TrimSpace(image.AltText) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAltText returns an error wrapping ErrMissingAltText if alt text is
+// required and a photo of post has none.
+func (r *Repo) CheckAltText(ctx context.Context, post *Post) error {
+	if !r.requireAltText {
+		return nil
+	}
+	images, err := r.GetPostImages(ctx, post.ID)
+	if err != nil {
+		return err
+	}
+	for i, image := range images {
+		if NeedsAltText(post.Type, image.Filename) && strings.TrimSpace(image.AltText) == "" {
+			return fmt.Errorf("%w: file %d of post %d is a photo without alt text", ErrMissingAltText, i+1, post.ID)
+		}
+	}
+	return nil
+}
+
+// GetPostImages returns the metadata of each file of a post, in order. Files
+// without metadata have empty alt text and no user tags.
+func (r *Repo) GetPostImages(ctx context.Context, postID int64) ([]Image, error) {
+	post, err := r.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	q := db.New(r.db)
+	rows, err := q.GetPostImages(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting post images: %w", err)
+	}
+	tagRows, err := q.GetPostUserTags(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting post user tags: %w", err)
+	}
+
+	images := make([]Image, len(post.ImageFilenames))
+	byFilename := make(map[string]*Image, len(images))
+	for i, filename := range post.ImageFilenames {
+		images[i] = Image{Filename: filename, UserTags: []UserTag{}}
+		byFilename[filename] = &images[i]
+	}
+	for _, row := range rows {
+		if image, ok := byFilename[row.Filename]; ok {
+			image.AltText = row.AltText
+		}
+	}
+	for _, row := range tagRows {
+		if image, ok := byFilename[row.Filename]; ok {
+			image.UserTags = append(image.UserTags, UserTag{Username: row.Username, X: row.X, Y: row.Y})
+		}
+	}
+	return images, nil
+}
+
+// SetPostImages replaces the metadata of the files of a post. Each image
+// must name a file of the post; files left out get no metadata. It returns
+// an error wrapping ErrInvalidMetadata if an image is invalid.
+func (r *Repo) SetPostImages(ctx context.Context, postID int64, images []Image) error {
+	post, err := r.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	files := make(map[string]bool, len(post.ImageFilenames))
+	for _, filename := range post.ImageFilenames {
+		files[filename] = true
+	}
+	seen := make(map[string]bool, len(images))
+	for _, image := range images {
+		if !files[image.Filename] {
+			return fmt.Errorf("%w: post %d has no file %q", ErrInvalidMetadata, postID, image.Filename)
+		}
+		if seen[image.Filename] {
+			return fmt.Errorf("%w: file %q is listed twice", ErrInvalidMetadata, image.Filename)
+		}
+		seen[image.Filename] = true
+		if err := checkImage(image); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := db.New(r.db).WithTx(tx)
+	if err := q.DeletePostImages(ctx, postID); err != nil {
+		return fmt.Errorf("error clearing post images: %w", err)
+	}
+	if err := q.DeletePostUserTags(ctx, postID); err != nil {
+		return fmt.Errorf("error clearing post user tags: %w", err)
+	}
+	for _, image := range images {
+		err := q.InsertPostImage(ctx, db.InsertPostImageParams{
+			PostID:   postID,
+			Filename: image.Filename,
+			AltText:  strings.TrimSpace(image.AltText),
+		})
+		if err != nil {
+			return fmt.Errorf("error inserting post image: %w", err)
+		}
+		for _, tag := range image.UserTags {
+			err := q.InsertPostUserTag(ctx, db.InsertPostUserTagParams{
+				PostID:   postID,
+				Filename: image.Filename,
+				Username: strings.TrimPrefix(tag.Username, "@"),
+				X:        tag.X,
+				Y:        tag.Y,
+			})
+			if err != nil {
+				return fmt.Errorf("error inserting post user tag: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func checkImage(image Image) error {
+	if n := utf8.RuneCountInString(strings.TrimSpace(image.AltText)); n > MaxAltTextLength {
+		return fmt.Errorf("%w: alt text of %q is %d characters, more than %d", ErrInvalidMetadata, image.Filename, n, MaxAltTextLength)
+	}
+	if len(image.UserTags) > MaxUserTags {
+		return fmt.Errorf("%w: %q has %d user tags, more than %d", ErrInvalidMetadata, image.Filename, len(image.UserTags), MaxUserTags)
+	}
+	for _, tag := range image.UserTags {
+		if !usernameRe.MatchString(strings.TrimPrefix(tag.Username, "@")) {
+			return fmt.Errorf("%w: invalid username %q", ErrInvalidMetadata, tag.Username)
+		}
+		if tag.X < 0 || tag.X > 1 || tag.Y < 0 || tag.Y > 1 {
+			return fmt.Errorf("%w: user tag %s at (%g, %g) is not within the image", ErrInvalidMetadata, tag.Username, tag.X, tag.Y)
+		}
+	}
+	return nil
+}
+
+// SetPostLocation tags a post with a location, or removes its location if
+// loc is absent. It returns an error wrapping ErrInvalidMetadata if loc is
+// invalid.
+func (r *Repo) SetPostLocation(ctx context.Context, postID int64, loc mo.Option[Location]) error {
+	if _, err := r.GetPost(ctx, postID); err != nil {
+		return err
+	}
+	params := db.UpdatePostLocationParams{ID: postID}
+	if l, ok := loc.Get(); ok {
+		l.Name = strings.TrimSpace(l.Name)
+		switch {
+		case l.Name == "":
+			return fmt.Errorf("%w: location name is empty", ErrInvalidMetadata)
+		case l.Lat < -90 || l.Lat > 90:
+			return fmt.Errorf("%w: latitude %g is not between -90 and 90", ErrInvalidMetadata, l.Lat)
+		case l.Lng < -180 || l.Lng > 180:
+			return fmt.Errorf("%w: longitude %g is not between -180 and 180", ErrInvalidMetadata, l.Lng)
+		}
+		params.LocationName = l.Name
+		params.LocationLat = sql.NullFloat64{Float64: l.Lat, Valid: true}
+		params.LocationLng = sql.NullFloat64{Float64: l.Lng, Valid: true}
+	}
+	q := db.New(r.db)
+	if err := q.UpdatePostLocation(ctx, params); err != nil {
+		return fmt.Errorf("error updating post location: %w", err)
+	}
+	return nil
+}
@@ -19,43 +19,41 @@ import (
 )
 
 var (
-	EstTimezone         *time.Location
-	allowedExtensionsRe = regexp.MustCompile(`\.(jpe?g|png|gif)$`)
+	allowedExtensionsRe = regexp.MustCompile(`(?i)\.(jpe?g|png|gif|mp4|mov)$`)
 
 	ErrStorageFull      = fmt.Errorf("storage full")
 	ErrInvalidExtension = fmt.Errorf("invalid file extension")
 	ErrPostNotFound     = fmt.Errorf("post not found")
 )
 
-func init() {
-	var err error
-	EstTimezone, err = time.LoadLocation("America/New_York")
-	if err != nil {
-		panic(fmt.Errorf("failed to load timezone: %w", err))
-	}
-}
-
-type EstTime struct {
-	time.Time
-}
-
-func (t EstTime) String() string {
-	return fmt.Sprintf("%s EST", t.In(EstTimezone).Format("2006-01-02 15:04:05"))
-}
-
-func (t EstTime) zulu() string {
-	return t.Format(time.RFC3339)
+// zulu formats t the way times are stored in the database.
+func zulu(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
 }
 
 type Post struct {
 	ID             int64
 	ImageFilenames []string
 	Caption        string
-	Timestamp      EstTime
+	Timestamp      time.Time
 	Position       int64
 	PhotoCount     int64
-	IsPosted       bool
-	PostedAt       mo.Option[EstTime]
+	Status         PostStatus
+	PostedAt       mo.Option[time.Time]
+	ScheduledAt    mo.Option[time.Time]
+	Type           PostType
+	Queue          Queue
+	// CoverFilename is the cover image of a reel, or empty.
+	CoverFilename string
+	Location      mo.Option[Location]
+	// FirstComment is commented on the post right after it is published,
+	// or empty.
+	FirstComment string
+	// MediaID is the Instagram ID of the published post.
+	MediaID       string
+	CommentStatus CommentStatus
+	// CommentError is why the first comment failed.
+	CommentError string
 }
 
 func (p *Post) fromDb(row *db.Post) {
@@ -63,16 +61,50 @@ func (p *Post) fromDb(row *db.Post) {
 	p.Caption = row.Caption
 	p.Position = row.Position
 	p.PhotoCount = row.PhotoCount
-	p.IsPosted = row.IsPosted == 1
+	p.Status = PostStatus(row.Status)
+	p.Type = PostType(row.PostType)
+	p.Queue = Queue(row.Queue)
+	p.CoverFilename = row.CoverFilename
+	p.FirstComment = row.FirstComment
+	p.MediaID = row.MediaID
+	p.CommentStatus = CommentStatus(row.CommentStatus)
+	p.CommentError = row.CommentError
 	p.ImageFilenames = strings.Split(row.ImageFilenames, ",")
 	t, _ := time.Parse(time.RFC3339, row.Timestamp)
-	p.Timestamp = EstTime{t}
+	p.Timestamp = t
 	if row.PostedAt.Valid {
 		t, _ := time.Parse(time.RFC3339, row.PostedAt.String)
-		p.PostedAt = mo.Some(EstTime{t})
+		p.PostedAt = mo.Some(t)
+	} else {
+		p.PostedAt = mo.None[time.Time]()
+	}
+	if row.ScheduledAt.Valid {
+		t, _ := time.Parse(time.RFC3339, row.ScheduledAt.String)
+		p.ScheduledAt = mo.Some(t)
+	} else {
+		p.ScheduledAt = mo.None[time.Time]()
+	}
+	if row.LocationName != "" && row.LocationLat.Valid && row.LocationLng.Valid {
+		p.Location = mo.Some(Location{
+			Name: row.LocationName,
+			Lat:  row.LocationLat.Float64,
+			Lng:  row.LocationLng.Float64,
+		})
 	} else {
-		p.PostedAt = mo.None[EstTime]()
+		p.Location = mo.None[Location]()
+	}
+}
+
+// In returns the post with its times in loc.
+func (p Post) In(loc *time.Location) Post {
+	p.Timestamp = p.Timestamp.In(loc)
+	if t, ok := p.PostedAt.Get(); ok {
+		p.PostedAt = mo.Some(t.In(loc))
+	}
+	if t, ok := p.ScheduledAt.Get(); ok {
+		p.ScheduledAt = mo.Some(t.In(loc))
 	}
+	return p
 }
 
 func (p *Post) toDb() db.InsertPostParams {
@@ -81,20 +113,37 @@ func (p *Post) toDb() db.InsertPostParams {
 		Caption:        p.Caption,
 		Position:       p.Position,
 		PhotoCount:     p.PhotoCount,
-		Timestamp:      p.Timestamp.zulu(),
-		IsPosted:       boolToInt(p.IsPosted),
+		Timestamp:      zulu(p.Timestamp),
+		Status:         string(p.Status),
+		PostType:       string(p.Type),
+		CoverFilename:  p.CoverFilename,
+		Queue:          string(p.Queue),
+		FirstComment:   p.FirstComment,
 	}
 }
 
 type UploadFile struct {
 	Header *multipart.FileHeader
 	File   *multipart.File
+	// AltText is the alt text of the file, if any.
+	AltText string
 }
 
+// InsertPost saves a new post of type postType, inferred from the files if
+// it is empty. cover is the cover image of a reel, or nil, and firstComment
+// is commented on the post once it is published, or empty. It returns an
+// error wrapping ErrInvalidMedia if the files do not suit the type, including
+// videos Instagram would reject, ErrInvalidMetadata if an alt text is too
+// long, or ErrMissingAltText if the post is queued with a photo that needs
+// alt text and has none.
 func (r *Repo) InsertPost(
 	ctx context.Context,
 	caption string,
 	files []UploadFile,
+	status PostStatus,
+	postType PostType,
+	cover *UploadFile,
+	firstComment string,
 ) (*Post, error) {
 	if r.storageFull() {
 		return nil, ErrStorageFull
@@ -102,50 +151,102 @@ func (r *Repo) InsertPost(
 	if len(files) == 0 {
 		return nil, fmt.Errorf("no files uploaded")
 	}
-	fileNames := make([]string, len(files))
+	if status != StatusDraft && status != StatusQueued {
+		return nil, fmt.Errorf("%w: new posts must be %s or %s", ErrInvalidStatus, StatusDraft, StatusQueued)
+	}
+	postType, err := checkMedia(postType, files, cover, r.maxCarouselItems)
+	if err != nil {
+		return nil, err
+	}
 	for i, file := range files {
-		ext := filepath.Ext(file.Header.Filename)
-		if !allowedExtensionsRe.MatchString(ext) {
-			return nil, ErrInvalidExtension
+		if err := checkImage(Image{Filename: file.Header.Filename, AltText: file.AltText}); err != nil {
+			return nil, err
 		}
-		newName := uuid.New().String() + ext
-		newPath := filepath.Join(r.varDir, postUploadDir, newName)
-		newFile, err := os.Create(newPath)
-		if err != nil {
-			return nil, fmt.Errorf("error creating file: %w", err)
+		missing := strings.TrimSpace(file.AltText) == "" && NeedsAltText(postType, file.Header.Filename)
+		if status == StatusQueued && r.requireAltText && missing {
+			return nil, fmt.Errorf("%w: file %d is a photo without alt text", ErrMissingAltText, i+1)
 		}
-		defer newFile.Close()
-		if _, err := io.Copy(newFile, *file.File); err != nil {
-			return nil, fmt.Errorf("error copying file: %w", err)
+	}
+	fileNames := make([]string, len(files))
+	for i, file := range files {
+		newName, err := r.saveUpload(file)
+		if err != nil {
+			return nil, err
 		}
 		fileNames[i] = newName
 	}
-	position, err := r.GetLastPositionOfUnpostedPost(ctx)
-	if err != nil {
-		if errors.Is(err, ErrPostNotFound) {
-			position = 0
-		} else {
+	var coverName string
+	if cover != nil {
+		if coverName, err = r.saveUpload(*cover); err != nil {
+			return nil, err
+		}
+	}
+	var position int64
+	if status == StatusQueued {
+		last, err := r.GetLastPositionOfUnpostedPost(ctx, postType.Queue())
+		if err != nil && !errors.Is(err, ErrPostNotFound) {
 			return nil, fmt.Errorf("could not generate position: %w", err)
 		}
+		position = last + 1
 	}
 	post := &Post{
 		Caption:        caption,
-		Position:       position + 1,
+		Position:       position,
 		PhotoCount:     int64(len(files)),
-		IsPosted:       false,
-		Timestamp:      EstTime{time.Now()},
+		Status:         status,
+		Timestamp:      time.Now(),
 		ImageFilenames: fileNames,
+		Type:           postType,
+		Queue:          postType.Queue(),
+		CoverFilename:  coverName,
+		FirstComment:   firstComment,
 	}
-	q := db.New(r.db)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := db.New(r.db).WithTx(tx)
 	row, err := q.InsertPost(ctx, post.toDb())
 	if err != nil {
 		return nil, fmt.Errorf("error inserting post: %w", err)
 	}
+	for i, file := range files {
+		if strings.TrimSpace(file.AltText) == "" {
+			continue
+		}
+		err := q.InsertPostImage(ctx, db.InsertPostImageParams{
+			PostID:   row.ID,
+			Filename: fileNames[i],
+			AltText:  strings.TrimSpace(file.AltText),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error inserting post image: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
 	newPost := &Post{}
 	newPost.fromDb(&row)
 	return newPost, nil
 }
 
+// saveUpload copies file into the upload directory under a new name, which
+// it returns.
+func (r *Repo) saveUpload(file UploadFile) (string, error) {
+	newName := uuid.New().String() + filepath.Ext(file.Header.Filename)
+	newFile, err := os.Create(filepath.Join(r.varDir, postUploadDir, newName))
+	if err != nil {
+		return "", fmt.Errorf("error creating file: %w", err)
+	}
+	defer newFile.Close()
+	if _, err := io.Copy(newFile, *file.File); err != nil {
+		return "", fmt.Errorf("error copying file: %w", err)
+	}
+	return newName, nil
+}
+
 func (r *Repo) GetAllPosts(ctx context.Context) ([]Post, error) {
 	q := db.New(r.db)
 	rows, err := q.GetAllPosts(ctx)
@@ -159,6 +260,21 @@ func (r *Repo) GetAllPosts(ctx context.Context) ([]Post, error) {
 	return posts, nil
 }
 
+// GetUnpostedPosts returns an ordered queue: its queued posts that are not
+// pinned, by position.
+func (r *Repo) GetUnpostedPosts(ctx context.Context, queue Queue) ([]Post, error) {
+	q := db.New(r.db)
+	rows, err := q.GetUnpostedPosts(ctx, string(queue))
+	if err != nil {
+		return nil, fmt.Errorf("error getting unposted posts: %w", err)
+	}
+	posts := make([]Post, len(rows))
+	for i, row := range rows {
+		posts[i].fromDb(&row)
+	}
+	return posts, nil
+}
+
 func (r *Repo) GetPost(ctx context.Context, id int64) (*Post, error) {
 	q := db.New(r.db)
 	row, err := q.GetPostById(ctx, id)
@@ -182,7 +298,23 @@ func (r *Repo) DeletePost(ctx context.Context, id int64) error {
 	if err := q.DeletePost(ctx, id); err != nil {
 		return fmt.Errorf("error deleting post: %w", err)
 	}
-	for _, filename := range post.ImageFilenames {
+	if err := q.DeletePostHashtagSetsForPost(ctx, id); err != nil {
+		return fmt.Errorf("error deleting post hashtag sets: %w", err)
+	}
+	if err := q.DeleteHashtagUsageForPost(ctx, id); err != nil {
+		return fmt.Errorf("error deleting post hashtag usage: %w", err)
+	}
+	if err := q.DeletePostImages(ctx, id); err != nil {
+		return fmt.Errorf("error deleting post images: %w", err)
+	}
+	if err := q.DeletePostUserTags(ctx, id); err != nil {
+		return fmt.Errorf("error deleting post user tags: %w", err)
+	}
+	filenames := post.ImageFilenames
+	if post.CoverFilename != "" {
+		filenames = append(filenames, post.CoverFilename)
+	}
+	for _, filename := range filenames {
 		err := os.Remove(filepath.Join(r.varDir, postUploadDir, filename))
 		if err != nil {
 			return fmt.Errorf("error deleting file: %w", err)
@@ -195,9 +327,9 @@ func (r *Repo) DeletePost(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (r *Repo) GetLastPositionOfUnpostedPost(ctx context.Context) (int64, error) {
+func (r *Repo) GetLastPositionOfUnpostedPost(ctx context.Context, queue Queue) (int64, error) {
 	q := db.New(r.db)
-	pos, err := q.GetLastPositionOfUnpostedPost(ctx)
+	pos, err := q.GetLastPositionOfUnpostedPost(ctx, string(queue))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, ErrPostNotFound
@@ -228,7 +360,13 @@ func (r *Repo) MovePost(ctx context.Context, id int64, up bool) error {
 	if err != nil {
 		return err
 	}
-	lastPosition, err := q.GetLastPositionOfUnpostedPost(ctx)
+	if post.Status != StatusQueued {
+		return ErrPostNotQueued
+	}
+	if post.ScheduledAt.IsPresent() {
+		return ErrPostPinned
+	}
+	lastPosition, err := q.GetLastPositionOfUnpostedPost(ctx, string(post.Queue))
 	if err != nil {
 		return fmt.Errorf("error getting last position of unposted post: %w", err)
 	}
@@ -244,7 +382,10 @@ func (r *Repo) MovePost(ctx context.Context, id int64, up bool) error {
 		}
 		targetPosition = post.Position + 1
 	}
-	postAtTarget, err := q.GetPostByPosition(ctx, targetPosition)
+	postAtTarget, err := q.GetPostByPosition(ctx, db.GetPostByPositionParams{
+		Position: targetPosition,
+		Queue:    string(post.Queue),
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("expected post at position %d to exist", targetPosition)
@@ -268,46 +409,9 @@ func (r *Repo) MovePost(ctx context.Context, id int64, up bool) error {
 	return nil
 }
 
-func (r *Repo) SetIsPostedValueOfPost(ctx context.Context, id int64, isPosted bool) error {
-	lastPosition, err := r.GetLastPositionOfUnpostedPost(ctx)
-	if err != nil {
-		if errors.Is(err, ErrPostNotFound) {
-			return ErrPostNotFound
-		}
-		return fmt.Errorf("error getting last position of unposted post: %w", err)
-	}
-	postedAt := sql.NullString{}
-	if isPosted {
-		now := EstTime{time.Now()}
-		postedAt.Valid = true
-		postedAt.String = now.zulu()
-	} else {
-		postedAt.Valid = false
-	}
-
+func (r *Repo) GetPostToPost(ctx context.Context, queue Queue) (*Post, error) {
 	q := db.New(r.db)
-	err = q.UpdateIsPostedValueOfPost(ctx, db.UpdateIsPostedValueOfPostParams{
-		ID:       id,
-		IsPosted: boolToInt(isPosted),
-		Position: lastPosition + 1,
-		PostedAt: postedAt,
-	})
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrPostNotFound
-		}
-		return fmt.Errorf("error updating is posted value: %w", err)
-	}
-	err = r.CleanPositions(ctx)
-	if err != nil {
-		return fmt.Errorf("error cleaning positions: %w", err)
-	}
-	return nil
-}
-
-func (r *Repo) GetPostToPost(ctx context.Context) (*Post, error) {
-	q := db.New(r.db)
-	row, err := q.GetPostToPost(ctx)
+	row, err := q.GetPostToPost(ctx, string(queue))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrPostNotFound
@@ -319,22 +423,25 @@ func (r *Repo) GetPostToPost(ctx context.Context) (*Post, error) {
 	return post, nil
 }
 
+// CleanPositions renumbers each queue from 1, closing any gaps.
 func (r *Repo) CleanPositions(ctx context.Context) error {
 	q := db.New(r.db)
-	posts, err := q.GetUnpostedPosts(ctx)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil
-		}
-		return fmt.Errorf("error getting unposted posts: %w", err)
-	}
-	for i, post := range posts {
-		err := q.UpdatePostPosition(ctx, db.UpdatePostPositionParams{
-			ID:       post.ID,
-			Position: int64(i) + 1,
-		})
+	for _, queue := range AllQueues {
+		posts, err := q.GetUnpostedPosts(ctx, string(queue))
 		if err != nil {
-			return fmt.Errorf("error updating post position: %w", err)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return fmt.Errorf("error getting unposted posts: %w", err)
+		}
+		for i, post := range posts {
+			err := q.UpdatePostPosition(ctx, db.UpdatePostPositionParams{
+				ID:       post.ID,
+				Position: int64(i) + 1,
+			})
+			if err != nil {
+				return fmt.Errorf("error updating post position: %w", err)
+			}
 		}
 	}
 	return nil
@@ -343,10 +450,3 @@ func (r *Repo) CleanPositions(ctx context.Context) error {
 func (r *Repo) GetPathForPost(filename string) string {
 	return filepath.Join(r.varDir, postUploadDir, filename)
 }
-
-func boolToInt(b bool) int64 {
-	if b {
-		return 1
-	}
-	return 0
-}
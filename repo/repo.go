@@ -1,32 +1,66 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	_ "modernc.org/sqlite"
 
 	"github.com/btschwartz12/isza/repo/db"
+	"github.com/btschwartz12/isza/tracing"
 )
 
 const (
 	postUploadDir  = "posts"
 	dbName         = "isza.db"
 	maxStorageSize = 5000 << 20 // 5GB
+	migrationsDir  = "sql/migrations"
+	busyTimeoutMs  = 5000
+
+	// MaxCarouselItems is the most files Instagram allows in a carousel.
+	MaxCarouselItems = 20
 )
 
 type Repo struct {
 	logger *zap.SugaredLogger
 	db     *sql.DB
 	varDir string
+	loc    *time.Location
+	// maxCarouselItems is the most files a carousel may have.
+	maxCarouselItems int
+	requireAltText   bool
+}
+
+// Options are the settings of a Repo.
+type Options struct {
+	// MaxCarouselItems is the most files a carousel may have, from 2 to
+	// MaxCarouselItems.
+	MaxCarouselItems int
+	// RequireAltText keeps posts with photos that have no alt text out of
+	// the queue, and from being published.
+	RequireAltText bool
 }
 
-func NewRepo(logger *zap.SugaredLogger, varDir string) (*Repo, error) {
+// NewRepo opens the repository in varDir. loc is the instance time zone,
+// used for dates filled into captions.
+func NewRepo(logger *zap.SugaredLogger, varDir string, loc *time.Location, opts Options) (*Repo, error) {
+	if opts.MaxCarouselItems < 2 || opts.MaxCarouselItems > MaxCarouselItems {
+		return nil, fmt.Errorf("max carousel items must be between 2 and %d, not %d", MaxCarouselItems, opts.MaxCarouselItems)
+	}
 	r := &Repo{
-		logger: logger,
+		logger:           logger,
+		loc:              loc,
+		maxCarouselItems: opts.MaxCarouselItems,
+		requireAltText:   opts.RequireAltText,
 	}
 
 	if err := os.MkdirAll(varDir, 0755); err != nil {
@@ -38,19 +72,35 @@ func NewRepo(logger *zap.SugaredLogger, varDir string) (*Repo, error) {
 		return nil, fmt.Errorf("error creating post upload dir: %w", err)
 	}
 
-	conn, err := sql.Open("sqlite", filepath.Join(varDir, dbName))
+	// Concurrent writers wait for the lock rather than failing at once.
+	dsn := "file:" + filepath.Join(varDir, dbName) + "?_pragma=busy_timeout(" + strconv.Itoa(busyTimeoutMs) + ")"
+	conn, err := tracing.OpenDB("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("error opening database connection: %w", err)
 	}
 
-	if _, err := conn.Exec(string(db.Schema)); err != nil {
-		return nil, fmt.Errorf("error executing schema: %w", err)
+	if err := migrate(conn); err != nil {
+		return nil, fmt.Errorf("error migrating database: %w", err)
 	}
 
 	r.db = conn
+	if err := r.backfillHashtagUsage(context.Background()); err != nil {
+		return nil, fmt.Errorf("error backfilling hashtag usage: %w", err)
+	}
 	return r, nil
 }
 
+// MaxCarouselItems returns the most files a carousel may have.
+func (r *Repo) MaxCarouselItems() int {
+	return r.maxCarouselItems
+}
+
+// RequiresAltText reports whether photos need alt text before their post is
+// queued or published.
+func (r *Repo) RequiresAltText() bool {
+	return r.requireAltText
+}
+
 func (r *Repo) storageFull() bool {
 	var stat os.FileInfo
 	var err error
@@ -64,3 +114,88 @@ func (r *Repo) storageFull() bool {
 	}
 	return false
 }
+
+// Ping checks that the database answers a query.
+func (r *Repo) Ping(ctx context.Context) error {
+	var one int
+	if err := r.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("error querying database: %w", err)
+	}
+	return nil
+}
+
+// VarDir returns the directory the repository is stored in.
+func (r *Repo) VarDir() string {
+	return r.varDir
+}
+
+// PostsDir returns the directory uploaded images are stored in.
+func (r *Repo) PostsDir() string {
+	return filepath.Join(r.varDir, postUploadDir)
+}
+
+// StorageBytes returns the total size of the files in the var dir: the
+// database and the uploaded images.
+func (r *Repo) StorageBytes() (int64, error) {
+	var total int64
+	err := filepath.WalkDir(r.varDir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error walking var dir: %w", err)
+	}
+	return total, nil
+}
+
+// migrate applies every migration numbered above the database's
+// user_version, each in its own transaction.
+func migrate(conn *sql.DB) error {
+	var version int
+	if err := conn.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+	entries, err := fs.ReadDir(db.Migrations, migrationsDir)
+	if err != nil {
+		return fmt.Errorf("error reading migrations: %w", err)
+	}
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		n, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("invalid migration name %q: %w", entry.Name(), err)
+		}
+		if n <= version {
+			continue
+		}
+		stmts, err := fs.ReadFile(db.Migrations, path.Join(migrationsDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("error reading migration %q: %w", entry.Name(), err)
+		}
+		tx, err := conn.Begin()
+		if err != nil {
+			return fmt.Errorf("error starting transaction: %w", err)
+		}
+		if _, err := tx.Exec(string(stmts)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error applying migration %q: %w", entry.Name(), err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", n)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error updating schema version: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error committing migration %q: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
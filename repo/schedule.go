@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btschwartz12/isza/repo/db"
+)
+
+var (
+	ErrPostPinned        = fmt.Errorf("post is pinned to a publish time")
+	ErrScheduleInThePast = fmt.Errorf("scheduled time is in the past")
+)
+
+// PinPost pins a post to an exact publish time, taking it out of the ordered
+// queue. It is published once the time has passed, regardless of position.
+func (r *Repo) PinPost(ctx context.Context, id int64, at time.Time) error {
+	post, err := r.GetPost(ctx, id)
+	if err != nil {
+		return err
+	}
+	if post.Status == StatusPublishing || post.Status == StatusPosted {
+		return fmt.Errorf("%w: cannot pin a %s post", ErrInvalidTransition, post.Status)
+	}
+	if at.Before(time.Now()) {
+		return ErrScheduleInThePast
+	}
+	q := db.New(r.db)
+	err = q.UpdatePostSchedule(ctx, db.UpdatePostScheduleParams{
+		ScheduledAt: sql.NullString{String: zulu(at), Valid: true},
+		Position:    0,
+		ID:          id,
+	})
+	if err != nil {
+		return fmt.Errorf("error pinning post: %w", err)
+	}
+	if err := r.CleanPositions(ctx); err != nil {
+		return fmt.Errorf("error cleaning positions: %w", err)
+	}
+	return nil
+}
+
+// UnpinPost removes a post's publish time. A queued post goes back to the
+// end of the ordered queue.
+func (r *Repo) UnpinPost(ctx context.Context, id int64) error {
+	post, err := r.GetPost(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !post.ScheduledAt.IsPresent() {
+		return nil
+	}
+	var position int64
+	if post.Status == StatusQueued {
+		last, err := r.GetLastPositionOfUnpostedPost(ctx, post.Queue)
+		if err != nil && !errors.Is(err, ErrPostNotFound) {
+			return err
+		}
+		position = last + 1
+	}
+	q := db.New(r.db)
+	err = q.UpdatePostSchedule(ctx, db.UpdatePostScheduleParams{
+		ScheduledAt: sql.NullString{},
+		Position:    position,
+		ID:          id,
+	})
+	if err != nil {
+		return fmt.Errorf("error unpinning post: %w", err)
+	}
+	return nil
+}
+
+// GetPinnedPosts returns the queued posts of a queue pinned to a publish time,
+// soonest first.
+func (r *Repo) GetPinnedPosts(ctx context.Context, queue Queue) ([]Post, error) {
+	q := db.New(r.db)
+	rows, err := q.GetPinnedPosts(ctx, string(queue))
+	if err != nil {
+		return nil, fmt.Errorf("error getting pinned posts: %w", err)
+	}
+	posts := make([]Post, len(rows))
+	for i, row := range rows {
+		posts[i].fromDb(&row)
+	}
+	return posts, nil
+}
+
+// GetDuePinnedPost returns the queued pinned post of a queue whose publish
+// time passed longest before now.
+func (r *Repo) GetDuePinnedPost(ctx context.Context, queue Queue, now time.Time) (*Post, error) {
+	q := db.New(r.db)
+	row, err := q.GetDuePinnedPost(ctx, db.GetDuePinnedPostParams{
+		ScheduledAt: sql.NullString{String: zulu(now), Valid: true},
+		Queue:       string(queue),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPostNotFound
+		}
+		return nil, fmt.Errorf("error getting due pinned post: %w", err)
+	}
+	post := &Post{}
+	post.fromDb(&row)
+	return post, nil
+}
@@ -0,0 +1,230 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btschwartz12/isza/repo/db"
+	"github.com/samber/mo"
+)
+
+type PostStatus string
+
+const (
+	StatusDraft      PostStatus = "draft"
+	StatusQueued     PostStatus = "queued"
+	StatusPublishing PostStatus = "publishing"
+	StatusPosted     PostStatus = "posted"
+	StatusFailed     PostStatus = "failed"
+	// StatusInterrupted is a post whose publish was cut short by a shutdown
+	// or crash. It may or may not have gone out, so it needs a person to
+	// check the account and mark it as posted or queue it again.
+	StatusInterrupted PostStatus = "interrupted"
+	StatusArchived    PostStatus = "archived"
+)
+
+var (
+	ErrInvalidStatus     = fmt.Errorf("invalid post status")
+	ErrInvalidTransition = fmt.Errorf("invalid post status transition")
+	ErrPostNotQueued     = fmt.Errorf("post is not queued")
+	ErrPostClaimed       = fmt.Errorf("post is already being published")
+
+	// AllStatuses lists every post status in lifecycle order.
+	AllStatuses = []PostStatus{
+		StatusDraft,
+		StatusQueued,
+		StatusPublishing,
+		StatusPosted,
+		StatusFailed,
+		StatusInterrupted,
+		StatusArchived,
+	}
+
+	// transitions maps each status to the statuses a post may move to from
+	// it. Only queued posts hold a position in the queue. Posts only become
+	// publishing through ClaimPost, which takes a lease on them.
+	transitions = map[PostStatus][]PostStatus{
+		StatusDraft:       {StatusQueued, StatusArchived},
+		StatusQueued:      {StatusDraft, StatusArchived},
+		StatusPublishing:  {StatusPosted, StatusFailed, StatusInterrupted},
+		StatusPosted:      {StatusQueued, StatusArchived},
+		StatusFailed:      {StatusQueued, StatusDraft, StatusArchived},
+		StatusInterrupted: {StatusPosted, StatusQueued, StatusArchived},
+		StatusArchived:    {StatusDraft, StatusQueued},
+	}
+)
+
+func ParseStatus(s string) (PostStatus, error) {
+	for _, status := range AllStatuses {
+		if string(status) == s {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidStatus, s)
+}
+
+// CanTransition reports whether a post may move from one status to another.
+func (s PostStatus) CanTransition(to PostStatus) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses returns the statuses a person may move the post to. A post
+// being published is left to the publisher.
+func (p *Post) NextStatuses() []PostStatus {
+	if p.Status == StatusPublishing {
+		return nil
+	}
+	return transitions[p.Status]
+}
+
+func (r *Repo) GetPostsByStatus(ctx context.Context, status PostStatus) ([]Post, error) {
+	q := db.New(r.db)
+	rows, err := q.GetPostsByStatus(ctx, string(status))
+	if err != nil {
+		return nil, fmt.Errorf("error getting posts by status: %w", err)
+	}
+	posts := make([]Post, len(rows))
+	for i, row := range rows {
+		posts[i].fromDb(&row)
+	}
+	return posts, nil
+}
+
+// SetPostStatus moves a post to a new status on a person's request. Only the
+// publisher moves posts out of publishing, since a publish may be running,
+// so it returns an error wrapping ErrInvalidTransition for those posts. It
+// returns an error wrapping ErrMissingAltText if the post cannot be queued
+// for want of alt text.
+func (r *Repo) SetPostStatus(ctx context.Context, id int64, to PostStatus) error {
+	return r.transitionPost(ctx, id, to, true)
+}
+
+// TransitionPost moves a post to a new status, enforcing the lifecycle state
+// machine. Posts entering the queue are placed at the end of it. It is for
+// the publisher; requests from people go through SetPostStatus.
+func (r *Repo) TransitionPost(ctx context.Context, id int64, to PostStatus) error {
+	return r.transitionPost(ctx, id, to, false)
+}
+
+func (r *Repo) transitionPost(ctx context.Context, id int64, to PostStatus, manual bool) error {
+	post, err := r.GetPost(ctx, id)
+	if err != nil {
+		return err
+	}
+	if manual && post.Status == StatusPublishing {
+		return fmt.Errorf("%w: post %d is being published", ErrInvalidTransition, id)
+	}
+	if !post.Status.CanTransition(to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, post.Status, to)
+	}
+	if to == StatusQueued {
+		if err := r.CheckAltText(ctx, post); err != nil {
+			return err
+		}
+	}
+
+	// A pin whose time has already passed would publish the post as soon as
+	// it is queued again, so it is dropped.
+	stalePin := post.ScheduledAt.IsPresent() && post.ScheduledAt.MustGet().Before(time.Now())
+	if to == StatusQueued && stalePin {
+		if err := r.UnpinPost(ctx, id); err != nil {
+			return err
+		}
+		post.ScheduledAt = mo.None[time.Time]()
+	}
+
+	var position int64
+	if to == StatusQueued && !post.ScheduledAt.IsPresent() {
+		last, err := r.GetLastPositionOfUnpostedPost(ctx, post.Queue)
+		if err != nil && !errors.Is(err, ErrPostNotFound) {
+			return err
+		}
+		position = last + 1
+	}
+	postedAt := sql.NullString{}
+	if to == StatusPosted {
+		postedAt.Valid = true
+		postedAt.String = zulu(time.Now())
+	} else if to == StatusArchived && post.PostedAt.IsPresent() {
+		postedAt.Valid = true
+		postedAt.String = zulu(post.PostedAt.MustGet())
+	}
+
+	// The update only applies if the status is unchanged, so a publish
+	// claiming the post in the meantime wins.
+	q := db.New(r.db)
+	n, err := q.UpdatePostStatus(ctx, db.UpdatePostStatusParams{
+		Status:     string(to),
+		Position:   position,
+		PostedAt:   postedAt,
+		ID:         id,
+		FromStatus: string(post.Status),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating post status: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: post %d is no longer %s", ErrInvalidTransition, id, post.Status)
+	}
+	if post.Status == StatusPosted && to == StatusQueued {
+		if err := q.DeleteHashtagUsageForPost(ctx, id); err != nil {
+			return fmt.Errorf("error deleting post hashtag usage: %w", err)
+		}
+	}
+	if post.Status == StatusQueued {
+		if err := r.CleanPositions(ctx); err != nil {
+			return fmt.Errorf("error cleaning positions: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClaimPost atomically moves a queued post to publishing, so that only one
+// caller can publish it. The claim is a lease that expires at until, after
+// which GetExpiredLeases reports the post in case its publisher died without
+// recording the result. It returns an error wrapping ErrPostClaimed if the
+// post is no longer queued.
+func (r *Repo) ClaimPost(ctx context.Context, id int64, until time.Time) error {
+	q := db.New(r.db)
+	n, err := q.ClaimPost(ctx, db.ClaimPostParams{
+		LeaseExpiresAt: sql.NullString{String: zulu(until), Valid: true},
+		ID:             id,
+	})
+	if err != nil {
+		return fmt.Errorf("error claiming post: %w", err)
+	}
+	if n == 0 {
+		post, err := r.GetPost(ctx, id)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: post %d is %s", ErrPostClaimed, id, post.Status)
+	}
+	if err := r.CleanPositions(ctx); err != nil {
+		return fmt.Errorf("error cleaning positions: %w", err)
+	}
+	return nil
+}
+
+// GetExpiredLeases returns the posts still publishing whose lease expired
+// before now.
+func (r *Repo) GetExpiredLeases(ctx context.Context, now time.Time) ([]Post, error) {
+	q := db.New(r.db)
+	rows, err := q.GetExpiredLeases(ctx, sql.NullString{String: zulu(now), Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("error getting expired leases: %w", err)
+	}
+	posts := make([]Post, len(rows))
+	for i, row := range rows {
+		posts[i].fromDb(&row)
+	}
+	return posts, nil
+}
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/btschwartz12/isza/caption"
+	"github.com/btschwartz12/isza/repo/db"
+)
+
+// Built-in caption variables, filled in when a post is published.
+const (
+	VarDate      = "date"
+	VarWeekday   = "weekday"
+	VarPostCount = "post_count"
+)
+
+var (
+	ErrVariableNotFound = fmt.Errorf("caption variable not found")
+	ErrInvalidVariable  = fmt.Errorf("invalid caption variable")
+
+	builtinVariables = map[string]bool{
+		VarDate:      true,
+		VarWeekday:   true,
+		VarPostCount: true,
+	}
+)
+
+type CaptionVariable struct {
+	Key   string
+	Value string
+}
+
+func (r *Repo) GetCaptionVariables(ctx context.Context, account string) ([]CaptionVariable, error) {
+	q := db.New(r.db)
+	rows, err := q.GetCaptionVariables(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("error getting caption variables: %w", err)
+	}
+	vars := make([]CaptionVariable, len(rows))
+	for i, row := range rows {
+		vars[i] = CaptionVariable{
+			Key:   row.Key,
+			Value: row.Value,
+		}
+	}
+	return vars, nil
+}
+
+func (r *Repo) SetCaptionVariable(ctx context.Context, account, key, value string) error {
+	if !caption.ValidVariableName(key) {
+		return fmt.Errorf("%w: %q must be lowercase letters, digits and underscores", ErrInvalidVariable, key)
+	}
+	if builtinVariables[key] {
+		return fmt.Errorf("%w: %q is a built-in variable", ErrInvalidVariable, key)
+	}
+	q := db.New(r.db)
+	err := q.UpsertCaptionVariable(ctx, db.UpsertCaptionVariableParams{
+		Account: account,
+		Key:     key,
+		Value:   value,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting caption variable: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) DeleteCaptionVariable(ctx context.Context, account, key string) error {
+	q := db.New(r.db)
+	n, err := q.DeleteCaptionVariable(ctx, db.DeleteCaptionVariableParams{
+		Account: account,
+		Key:     key,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting caption variable: %w", err)
+	}
+	if n == 0 {
+		return ErrVariableNotFound
+	}
+	return nil
+}
+
+// GetTemplateVariables returns the values available to caption templates for
+// a post published by account at publishAt: the built-in variables, with the
+// date in the instance time zone, plus the account's custom variables.
+func (r *Repo) GetTemplateVariables(ctx context.Context, account string, publishAt time.Time) (map[string]string, error) {
+	custom, err := r.GetCaptionVariables(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	q := db.New(r.db)
+	posted, err := q.CountPostedPosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error counting posted posts: %w", err)
+	}
+	vars := make(map[string]string, len(custom)+len(builtinVariables))
+	for _, v := range custom {
+		vars[v.Key] = v.Value
+	}
+	local := publishAt.In(r.loc)
+	vars[VarDate] = local.Format("January 2, 2006")
+	vars[VarWeekday] = local.Weekday().String()
+	vars[VarPostCount] = strconv.FormatInt(posted+1, 10)
+	return vars, nil
+}
+
+// RenderCaption renders text as a caption template for a post published by
+// account at publishAt, then appends the post's hashtag sets.
+func (r *Repo) RenderCaption(ctx context.Context, account string, postID int64, text string, publishAt time.Time) (string, error) {
+	sets, err := r.GetHashtagSetsForPost(ctx, postID)
+	if err != nil {
+		return "", err
+	}
+	return r.renderCaption(ctx, account, text, sets, publishAt)
+}
+
+// ComposeCaption renders text as a caption template for a post published by
+// account at publishAt, then appends the hashtag sets with IDs setIDs, for a
+// post whose sets are not saved yet. It returns an error wrapping
+// caption.ErrInvalidCaption if the template references a variable that is
+// not defined, or ErrHashtagSetNotFound if a set does not exist.
+func (r *Repo) ComposeCaption(ctx context.Context, account, text string, setIDs []int64, publishAt time.Time) (string, error) {
+	sets, err := r.GetHashtagSets(ctx, setIDs)
+	if err != nil {
+		return "", err
+	}
+	return r.renderCaption(ctx, account, text, sets, publishAt)
+}
+
+func (r *Repo) renderCaption(ctx context.Context, account, text string, sets []HashtagSet, publishAt time.Time) (string, error) {
+	vars, err := r.GetTemplateVariables(ctx, account, publishAt)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := caption.Render(text, vars)
+	if err != nil {
+		return "", err
+	}
+	return AppendHashtagSets(rendered, sets), nil
+}
+
+// AppendHashtagSets appends the hashtags of sets to text, skipping those it
+// already has.
+func AppendHashtagSets(text string, sets []HashtagSet) string {
+	for _, set := range sets {
+		text = caption.AppendHashtags(text, set.Hashtags)
+	}
+	return text
+}
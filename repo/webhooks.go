@@ -0,0 +1,269 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/isza/repo/db"
+	"github.com/samber/mo"
+)
+
+// DeliveryStatus is where a webhook delivery is in its lifecycle.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+var (
+	ErrWebhookNotFound = fmt.Errorf("webhook not found")
+	ErrInvalidWebhook  = fmt.Errorf("invalid webhook")
+)
+
+// Webhook is a subscription that receives a signed JSON payload for each of
+// its events.
+type Webhook struct {
+	ID  int64
+	URL string
+	// Secret keys the signatures of deliveries.
+	Secret    string
+	Events    []string
+	Timestamp time.Time
+}
+
+func (h *Webhook) fromDb(row *db.Webhook) {
+	h.ID = row.ID
+	h.URL = row.URL
+	h.Secret = row.Secret
+	h.Events = strings.Split(row.Events, ",")
+	h.Timestamp, _ = time.Parse(time.RFC3339, row.Timestamp)
+}
+
+// Subscribes reports whether the webhook wants event.
+func (h *Webhook) Subscribes(event string) bool {
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook.
+type WebhookDelivery struct {
+	ID            int64
+	WebhookID     int64
+	Event         string
+	Payload       string
+	Status        DeliveryStatus
+	Attempts      int64
+	ResponseCode  int64
+	Error         string
+	NextAttemptAt mo.Option[time.Time]
+	Timestamp     time.Time
+}
+
+func (d *WebhookDelivery) fromDb(row *db.WebhookDelivery) {
+	d.ID = row.ID
+	d.WebhookID = row.WebhookID
+	d.Event = row.Event
+	d.Payload = row.Payload
+	d.Status = DeliveryStatus(row.Status)
+	d.Attempts = row.Attempts
+	d.ResponseCode = row.ResponseCode
+	d.Error = row.Error
+	if row.NextAttemptAt.Valid {
+		t, _ := time.Parse(time.RFC3339, row.NextAttemptAt.String)
+		d.NextAttemptAt = mo.Some(t)
+	} else {
+		d.NextAttemptAt = mo.None[time.Time]()
+	}
+	d.Timestamp, _ = time.Parse(time.RFC3339, row.Timestamp)
+}
+
+func validateWebhook(rawURL, secret string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if secret == "" {
+		return fmt.Errorf("%w: secret is required", ErrInvalidWebhook)
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+func (r *Repo) InsertWebhook(ctx context.Context, rawURL, secret string, events []string) (*Webhook, error) {
+	if err := validateWebhook(rawURL, secret, events); err != nil {
+		return nil, err
+	}
+	q := db.New(r.db)
+	row, err := q.InsertWebhook(ctx, db.InsertWebhookParams{
+		URL:       rawURL,
+		Secret:    secret,
+		Events:    strings.Join(events, ","),
+		Timestamp: zulu(time.Now()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error inserting webhook: %w", err)
+	}
+	hook := &Webhook{}
+	hook.fromDb(&row)
+	return hook, nil
+}
+
+func (r *Repo) GetAllWebhooks(ctx context.Context) ([]Webhook, error) {
+	q := db.New(r.db)
+	rows, err := q.GetAllWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting all webhooks: %w", err)
+	}
+	hooks := make([]Webhook, len(rows))
+	for i, row := range rows {
+		hooks[i].fromDb(&row)
+	}
+	return hooks, nil
+}
+
+func (r *Repo) GetWebhook(ctx context.Context, id int64) (*Webhook, error) {
+	q := db.New(r.db)
+	row, err := q.GetWebhookById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("error getting webhook: %w", err)
+	}
+	hook := &Webhook{}
+	hook.fromDb(&row)
+	return hook, nil
+}
+
+func (r *Repo) UpdateWebhook(ctx context.Context, id int64, rawURL, secret string, events []string) error {
+	if err := validateWebhook(rawURL, secret, events); err != nil {
+		return err
+	}
+	q := db.New(r.db)
+	n, err := q.UpdateWebhook(ctx, db.UpdateWebhookParams{
+		URL:    rawURL,
+		Secret: secret,
+		Events: strings.Join(events, ","),
+		ID:     id,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating webhook: %w", err)
+	}
+	if n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DeleteWebhook deletes a webhook along with its delivery log.
+func (r *Repo) DeleteWebhook(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := db.New(r.db).WithTx(tx)
+	n, err := q.DeleteWebhook(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	if n == 0 {
+		return ErrWebhookNotFound
+	}
+	if err := q.DeleteWebhookDeliveriesForWebhook(ctx, id); err != nil {
+		return fmt.Errorf("error deleting webhook deliveries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// InsertWebhookDelivery records a pending delivery, due immediately.
+func (r *Repo) InsertWebhookDelivery(ctx context.Context, webhookID int64, event, payload string) (*WebhookDelivery, error) {
+	now := zulu(time.Now())
+	q := db.New(r.db)
+	row, err := q.InsertWebhookDelivery(ctx, db.InsertWebhookDeliveryParams{
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       payload,
+		NextAttemptAt: sql.NullString{String: now, Valid: true},
+		Timestamp:     now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error inserting webhook delivery: %w", err)
+	}
+	d := &WebhookDelivery{}
+	d.fromDb(&row)
+	return d, nil
+}
+
+// GetDueWebhookDeliveries returns pending deliveries whose next attempt is at
+// or before now, oldest first.
+func (r *Repo) GetDueWebhookDeliveries(ctx context.Context, now time.Time) ([]WebhookDelivery, error) {
+	q := db.New(r.db)
+	rows, err := q.GetDueWebhookDeliveries(ctx, sql.NullString{String: zulu(now), Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("error getting due webhook deliveries: %w", err)
+	}
+	deliveries := make([]WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i].fromDb(&row)
+	}
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt.
+func (r *Repo) UpdateWebhookDelivery(ctx context.Context, d *WebhookDelivery) error {
+	nextAttemptAt := sql.NullString{}
+	if t, ok := d.NextAttemptAt.Get(); ok {
+		nextAttemptAt = sql.NullString{String: zulu(t), Valid: true}
+	}
+	q := db.New(r.db)
+	err := q.UpdateWebhookDelivery(ctx, db.UpdateWebhookDeliveryParams{
+		Status:        string(d.Status),
+		Attempts:      d.Attempts,
+		ResponseCode:  d.ResponseCode,
+		Error:         d.Error,
+		NextAttemptAt: nextAttemptAt,
+		ID:            d.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns a webhook's most recent deliveries, newest
+// first.
+func (r *Repo) GetWebhookDeliveries(ctx context.Context, webhookID int64, limit int64) ([]WebhookDelivery, error) {
+	if _, err := r.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	q := db.New(r.db)
+	rows, err := q.GetWebhookDeliveries(ctx, db.GetWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook deliveries: %w", err)
+	}
+	deliveries := make([]WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i].fromDb(&row)
+	}
+	return deliveries, nil
+}
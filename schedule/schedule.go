@@ -0,0 +1,204 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/isza/repo"
+)
+
+// Schedule is the set of daily times at which the front of the queue is
+// published.
+type Schedule struct {
+	minutes []int // minutes after midnight, ascending
+	loc     *time.Location
+}
+
+// Parse parses a comma separated list of 24-hour times, e.g. "12:00,18:00",
+// interpreted in loc.
+func Parse(s string, loc *time.Location) (*Schedule, error) {
+	sched := &Schedule{loc: loc}
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		t, err := time.Parse("15:04", field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid post time %q: %w", field, err)
+		}
+		m := t.Hour()*60 + t.Minute()
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		sched.minutes = append(sched.minutes, m)
+	}
+	if len(sched.minutes) == 0 {
+		return nil, fmt.Errorf("at least one post time is required")
+	}
+	sort.Ints(sched.minutes)
+	return sched, nil
+}
+
+// Location returns the time zone the schedule's times are in.
+func (s *Schedule) Location() *time.Location {
+	return s.loc
+}
+
+// Next returns the first publish slot strictly after t. Slots keep their
+// wall clock times on days the clocks change.
+func (s *Schedule) Next(t time.Time) time.Time {
+	local := t.In(s.loc)
+	year, month, day := local.Date()
+	for {
+		for _, m := range s.minutes {
+			slot := time.Date(year, month, day, m/60, m%60, 0, 0, s.loc)
+			if slot.After(t) {
+				return slot
+			}
+		}
+		day++
+	}
+}
+
+// PerDay returns the number of publish slots in a day.
+func (s *Schedule) PerDay() int {
+	return len(s.minutes)
+}
+
+// String formats the schedule for display, e.g. "12:00 PM, 6:00 PM".
+func (s *Schedule) String() string {
+	times := make([]string, len(s.minutes))
+	for i, m := range s.minutes {
+		times[i] = time.Date(0, 1, 1, m/60, m%60, 0, 0, time.UTC).Format("3:04 PM")
+	}
+	return strings.Join(times, ", ")
+}
+
+// blackoutHorizon bounds how far ahead the schedule looks for the end of a
+// run of blackouts, so that rules covering every slot cannot loop forever.
+const blackoutHorizon = 366 * 24 * time.Hour
+
+// Paused returns the blackout covering t, if any, and when publishing
+// resumes: the end of that blackout or of any blackouts it runs into.
+func (s *Schedule) Paused(blackouts []repo.Blackout, t time.Time) (*repo.Blackout, time.Time, bool) {
+	var covering *repo.Blackout
+	resume := t
+	for resume.Sub(t) <= blackoutHorizon {
+		extended := false
+		for i := range blackouts {
+			if end, ok := blackouts[i].Covers(resume, s.loc); ok {
+				if covering == nil {
+					covering = &blackouts[i]
+				}
+				resume = end
+				extended = true
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+	return covering, resume, covering != nil
+}
+
+// NextOpen returns the first publish slot strictly after t that is not
+// covered by a blackout. It reports false if there is none within a year.
+func (s *Schedule) NextOpen(t time.Time, blackouts []repo.Blackout) (time.Time, bool) {
+	slot := s.Next(t)
+	for slot.Sub(t) <= blackoutHorizon {
+		_, resume, paused := s.Paused(blackouts, slot)
+		if !paused {
+			return slot, true
+		}
+		slot = s.Next(resume.Add(-time.Nanosecond))
+	}
+	return time.Time{}, false
+}
+
+// Projection is the expected publish time of a post.
+type Projection struct {
+	Post      repo.Post
+	PublishAt time.Time
+	Pinned    bool
+	// Label is a short approximate description of PublishAt, e.g. "~Tue 6 PM".
+	Label string
+}
+
+// Project returns the expected publish time of every queued post as of now.
+// Ordered posts take consecutive open slots of the schedule; pinned posts go
+// out at their pinned time, or when the blackout covering it ends. Publish
+// times and labels are in now's location. The result is sorted by publish
+// time, with posts that cannot be placed last.
+func Project(queue, pinned []repo.Post, blackouts []repo.Blackout, s *Schedule, now time.Time) []Projection {
+	projections := make([]Projection, 0, len(queue)+len(pinned))
+	slot, open := now, true
+	for _, post := range queue {
+		if open {
+			slot, open = s.NextOpen(slot, blackouts)
+		}
+		p := Projection{Post: post, Label: "blacked out"}
+		if open {
+			p.PublishAt = slot.In(now.Location())
+			p.Label = label(p.PublishAt, now)
+		}
+		projections = append(projections, p)
+	}
+	for _, post := range pinned {
+		at := post.ScheduledAt.MustGet()
+		if _, resume, paused := s.Paused(blackouts, at); paused {
+			at = resume
+		}
+		at = at.In(now.Location())
+		projections = append(projections, Projection{
+			Post:      post,
+			PublishAt: at,
+			Pinned:    true,
+			Label:     label(at, now),
+		})
+	}
+	sort.SliceStable(projections, func(i, j int) bool {
+		a, b := projections[i].PublishAt, projections[j].PublishAt
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.Before(b)
+	})
+	return projections
+}
+
+// ProjectQueue loads a queue, its pinned posts and the blackouts and projects
+// them onto s as of now, in now's location.
+func ProjectQueue(ctx context.Context, rpo *repo.Repo, queue repo.Queue, s *Schedule, now time.Time) ([]Projection, error) {
+	posts, err := rpo.GetUnpostedPosts(ctx, queue)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := rpo.GetPinnedPosts(ctx, queue)
+	if err != nil {
+		return nil, err
+	}
+	blackouts, err := rpo.GetAllBlackouts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Project(posts, pinned, blackouts, s, now), nil
+}
+
+// label describes t approximately, including the date when it is more than
+// six days after now.
+func label(t, now time.Time) string {
+	layout := "Mon 3:04 PM"
+	if t.Minute() == 0 {
+		layout = "Mon 3 PM"
+	}
+	if t.Sub(now) > 6*24*time.Hour {
+		layout = "Mon Jan 2 " + layout[4:]
+	}
+	return "~" + t.Format(layout)
+}
@@ -0,0 +1,124 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "12:00", want: "12:00 PM"},
+		{in: "18:00,12:00", want: "12:00 PM, 6:00 PM"},
+		{in: " 09:30 , 21:15 ", want: "9:30 AM, 9:15 PM"},
+		{in: "12:00,12:00,00:00", want: "12:00 AM, 12:00 PM"},
+		{in: "12:00,,", want: "12:00 PM"},
+		{in: "", wantErr: true},
+		{in: " , ", wantErr: true},
+		{in: "24:00", wantErr: true},
+		{in: "12:60", wantErr: true},
+		{in: "noon", wantErr: true},
+		{in: "6pm", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			s, err := Parse(tt.in, time.UTC)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %v, want an error", tt.in, s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.in, err)
+			}
+			if got := s.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	tests := []struct {
+		name  string
+		sched string
+		loc   *time.Location
+		t     time.Time
+		want  time.Time
+	}{
+		{
+			name:  "later today",
+			sched: "12:00,18:00",
+			loc:   time.UTC,
+			t:     time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "strictly after a slot",
+			sched: "12:00,18:00",
+			loc:   time.UTC,
+			t:     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "tomorrow",
+			sched: "12:00,18:00",
+			loc:   time.UTC,
+			t:     time.Date(2024, 5, 1, 18, 0, 1, 0, time.UTC),
+			want:  time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "across a year",
+			sched: "08:00",
+			loc:   time.UTC,
+			t:     time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC),
+			want:  time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "in the schedule's location",
+			sched: "09:00",
+			loc:   ny,
+			t:     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 5, 1, 9, 0, 0, 0, ny),
+		},
+		{
+			name:  "day clocks go forward",
+			sched: "18:00",
+			loc:   ny,
+			t:     time.Date(2024, 3, 10, 0, 0, 0, 0, ny),
+			want:  time.Date(2024, 3, 10, 18, 0, 0, 0, ny),
+		},
+		{
+			name:  "day clocks go back",
+			sched: "18:00",
+			loc:   ny,
+			t:     time.Date(2024, 11, 3, 0, 0, 0, 0, ny),
+			want:  time.Date(2024, 11, 3, 18, 0, 0, 0, ny),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.sched, tt.loc)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.sched, err)
+			}
+			if got := s.Next(tt.t); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/btschwartz12/isza/repo"
+	"github.com/go-chi/chi/v5"
+)
+
+type blackoutRequest struct {
+	Name       string    `json:"name"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Recurrence string    `json:"recurrence" enums:"none,weekly,yearly"`
+}
+
+// getAllBlackoutsHandler godoc
+// @Summary Get all blackouts
+// @Description Get all blackout periods during which nothing is published automatically
+// @Tags blackouts
+// @Produce json
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/blackouts [get]
+// @Success 200
+func (s *ApiServer) getAllBlackoutsHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	blackouts, err := s.rpo.GetAllBlackouts(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting all blackouts", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	for i := range blackouts {
+		blackouts[i] = blackouts[i].In(loc)
+	}
+
+	resp, err := json.MarshalIndent(blackouts, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling blackouts", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// getBlackoutHandler godoc
+// @Summary Get a blackout
+// @Description Get a blackout
+// @Tags blackouts
+// @Produce json
+// @Param id path int true "Blackout ID"
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/blackouts/{id} [get]
+// @Success 200
+func (s *ApiServer) getBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+
+	b, err := s.rpo.GetBlackout(r.Context(), id)
+	if err != nil {
+		if !writeBlackoutError(w, err) {
+			s.log(r).Errorw("error getting blackout", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp, err := json.MarshalIndent(b.In(loc), "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling blackout", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// createBlackoutHandler godoc
+// @Summary Create a blackout
+// @Description Create a blackout period (RFC 3339 times). A weekly or yearly blackout repeats its first occurrence on the wall clock
+// @Tags blackouts
+// @Accept json
+// @Produce json
+// @Param request body blackoutRequest true "Blackout"
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/blackouts [post]
+// @Security Bearer
+// @Success 201
+func (s *ApiServer) createBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	req, recurrence, ok := decodeBlackoutRequest(w, r)
+	if !ok {
+		return
+	}
+
+	b, err := s.rpo.InsertBlackout(r.Context(), req.Name, req.StartsAt, req.EndsAt, recurrence)
+	if err != nil {
+		if !writeBlackoutError(w, err) {
+			s.log(r).Errorw("error creating blackout", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp, err := json.MarshalIndent(b.In(loc), "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling blackout", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+	s.log(r).Infow("blackout created", "id", b.ID)
+}
+
+// updateBlackoutHandler godoc
+// @Summary Update a blackout
+// @Description Update a blackout period (RFC 3339 times)
+// @Tags blackouts
+// @Accept json
+// @Param id path int true "Blackout ID"
+// @Param request body blackoutRequest true "Blackout"
+// @Router /api/blackouts/{id} [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) updateBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+
+	req, recurrence, ok := decodeBlackoutRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err = s.rpo.UpdateBlackout(r.Context(), id, req.Name, req.StartsAt, req.EndsAt, recurrence)
+	if err != nil {
+		if !writeBlackoutError(w, err) {
+			s.log(r).Errorw("error updating blackout", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("blackout updated", "id", id)
+}
+
+// deleteBlackoutHandler godoc
+// @Summary Delete a blackout
+// @Description Delete a blackout period
+// @Tags blackouts
+// @Param id path int true "Blackout ID"
+// @Router /api/blackouts/{id} [delete]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) deleteBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.DeleteBlackout(r.Context(), id)
+	if err != nil {
+		if !writeBlackoutError(w, err) {
+			s.log(r).Errorw("error deleting blackout", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("blackout deleted", "id", id)
+}
+
+func decodeBlackoutRequest(w http.ResponseWriter, r *http.Request) (*blackoutRequest, repo.Recurrence, bool) {
+	var req blackoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, "", false
+	}
+	recurrence, err := repo.ParseRecurrence(req.Recurrence)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, "", false
+	}
+	return &req, recurrence, true
+}
+
+// writeBlackoutError writes the response for well-known blackout errors and
+// reports whether it did so.
+func writeBlackoutError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, repo.ErrBlackoutNotFound):
+		http.Error(w, "Blackout not found", http.StatusNotFound)
+	case errors.Is(err, repo.ErrInvalidBlackout):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/btschwartz12/isza/calendar"
+)
+
+// calendarFeedHandler godoc
+// @Summary Get the posting calendar feed
+// @Description iCalendar feed of queued posts at their projected publish slots and posted posts at the time they went out. It takes the calendar token, not the API token, which calendar apps can pass as a query parameter. The feed is off, and 404s, unless a calendar token is set.
+// @Tags calendar
+// @Produce text/calendar
+// @Param token query string false "Calendar token"
+// @Router /api/calendar.ics [get]
+// @Success 200
+func (s *ApiServer) calendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	events, err := calendar.Events(r.Context(), s.rpo, s.sched, now)
+	if err != nil {
+		s.log(r).Errorw("error getting calendar events", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="isza.ics"`)
+	err = calendar.WriteICS(w, "isza: "+s.instaUsername, events, now)
+	if err != nil {
+		s.log(r).Errorw("error writing calendar feed", "error", err)
+	}
+}
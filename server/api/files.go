@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/webhook"
+)
+
+// multipartMemory is how much of an upload is held in memory while it is
+// parsed; the rest goes to temporary files.
+const multipartMemory = 32 << 20
+
+type reorderFilesRequest struct {
+	Filenames []string `json:"filenames"`
+}
+
+// addPostFilesHandler godoc
+// @Summary Add files to a post
+// @Description Append files to a draft, queued or failed post, after its existing files. A feed post becomes a carousel. When alt text is required, a queued post must be left with alt text on every photo, or the response is 422
+// @Tags posts
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param file formData file true "File to add; repeat the field to add several"
+// @Param alt_text formData string false "Alt text of the file; repeat the field for each file, in the same order"
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/posts/{id}/files [post]
+// @Security Bearer
+// @Success 200
+func (s *ApiServer) addPostFilesHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadSize)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Upload is larger than %d MB", s.maxUploadSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+	fheaders := r.MultipartForm.File["file"]
+	if len(fheaders) == 0 {
+		http.Error(w, "At least one file is required", http.StatusBadRequest)
+		return
+	}
+	altTexts := r.MultipartForm.Value["alt_text"]
+	files := make([]repo.UploadFile, 0, len(fheaders))
+	for i, fheader := range fheaders {
+		file, err := fheader.Open()
+		if err != nil {
+			s.log(r).Errorw("error opening file", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+
+		var altText string
+		if i < len(altTexts) {
+			altText = altTexts[i]
+		}
+		files = append(files, repo.UploadFile{Header: fheader, File: &file, AltText: altText})
+	}
+
+	post, err := s.rpo.AddPostFiles(r.Context(), id, files)
+	if err != nil {
+		if !writeFilesError(w, err) {
+			s.log(r).Errorw("error adding post files", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+	s.hooks.EmitPost(r.Context(), webhook.EventPostFilesChanged, post, nil)
+	s.writePost(w, r, post, loc)
+	s.log(r).Infow("post files added", "id", id, "count", len(files))
+}
+
+// deletePostFileHandler godoc
+// @Summary Remove a file from a post
+// @Description Remove a file from a draft, queued or failed post and delete it, along with its alt text and user tags. A carousel left with one file becomes a feed post
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param filename path string true "Filename"
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/posts/{id}/files/{filename} [delete]
+// @Security Bearer
+// @Success 200
+func (s *ApiServer) deletePostFileHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+	filename := chi.URLParam(r, "filename")
+
+	post, err := s.rpo.RemovePostFile(r.Context(), id, filename)
+	if err != nil {
+		if !writeFilesError(w, err) {
+			s.log(r).Errorw("error removing post file", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+	s.hooks.EmitPost(r.Context(), webhook.EventPostFilesChanged, post, nil)
+	s.writePost(w, r, post, loc)
+	s.log(r).Infow("post file removed", "id", id, "filename", filename)
+}
+
+// reorderPostFilesHandler godoc
+// @Summary Reorder the files of a post
+// @Description Put the files of a draft, queued or failed post in a new order, which must list each of them once
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param request body reorderFilesRequest true "Filenames in their new order"
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/posts/{id}/files [put]
+// @Security Bearer
+// @Success 200
+func (s *ApiServer) reorderPostFilesHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req reorderFilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	post, err := s.rpo.ReorderPostFiles(r.Context(), id, req.Filenames)
+	if err != nil {
+		if !writeFilesError(w, err) {
+			s.log(r).Errorw("error reordering post files", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+	s.hooks.EmitPost(r.Context(), webhook.EventPostFilesChanged, post, nil)
+	s.writePost(w, r, post, loc)
+	s.log(r).Infow("post files reordered", "id", id)
+}
+
+// writeFilesError writes the response for errors changing a post's files
+// that are the client's fault, and reports whether it did.
+func writeFilesError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, repo.ErrPostNotFound):
+		http.Error(w, "Post not found", http.StatusNotFound)
+	case errors.Is(err, repo.ErrInvalidMedia), errors.Is(err, repo.ErrInvalidExtension), errors.Is(err, repo.ErrInvalidMetadata):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repo.ErrMissingAltText):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, repo.ErrPostNotEditable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repo.ErrStorageFull):
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *ApiServer) writePost(w http.ResponseWriter, r *http.Request, post *repo.Post, loc *time.Location) {
+	resp, err := json.MarshalIndent(post.In(loc), "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling post", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
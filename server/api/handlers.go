@@ -2,33 +2,60 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/btschwartz12/isza/caption"
 	"github.com/btschwartz12/isza/instagram"
+	"github.com/btschwartz12/isza/publisher"
 	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/webhook"
 	"github.com/go-chi/chi/v5"
 	_ "github.com/samber/mo"
 )
 
 // getAllPostsHandler godoc
 // @Summary Get all posts
-// @Description Get all posts
+// @Description Get all posts, optionally filtered by status
 // @Tags posts
 // @Produce json
+// @Param status query string false "Post status" Enums(draft, queued, publishing, posted, failed, interrupted, archived)
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
 // @Router /api/posts [get]
 // @Success 200
 func (s *ApiServer) getAllPostsHandler(w http.ResponseWriter, r *http.Request) {
-	posts, err := s.rpo.GetAllPosts(r.Context())
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	var posts []repo.Post
+	var err error
+	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
+		status, perr := repo.ParseStatus(statusStr)
+		if perr != nil {
+			http.Error(w, perr.Error(), http.StatusBadRequest)
+			return
+		}
+		posts, err = s.rpo.GetPostsByStatus(r.Context(), status)
+	} else {
+		posts, err = s.rpo.GetAllPosts(r.Context())
+	}
 	if err != nil {
-		s.logger.Errorw("error getting all posts", "error", err)
+		s.log(r).Errorw("error getting all posts", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	for i := range posts {
+		posts[i] = posts[i].In(loc)
+	}
 
 	resp, err := json.MarshalIndent(posts, "", "\t")
 	if err != nil {
-		s.logger.Errorw("error marshalling posts", "error", err)
+		s.log(r).Errorw("error marshalling posts", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -43,9 +70,15 @@ func (s *ApiServer) getAllPostsHandler(w http.ResponseWriter, r *http.Request) {
 // @Tags posts
 // @Produce json
 // @Param id path int true "Post ID"
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
 // @Router /api/posts/{id} [get]
 // @Success 200
 func (s *ApiServer) getPostHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	if idStr == "" {
 		http.Error(w, "Post ID is required", http.StatusBadRequest)
@@ -64,14 +97,14 @@ func (s *ApiServer) getPostHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Post not found", http.StatusNotFound)
 			return
 		}
-		s.logger.Errorw("error getting post", "error", err)
+		s.log(r).Errorw("error getting post", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	resp, err := json.MarshalIndent(post, "", "\t")
+	resp, err := json.MarshalIndent(post.In(loc), "", "\t")
 	if err != nil {
-		s.logger.Errorw("error marshalling post", "error", err)
+		s.log(r).Errorw("error marshalling post", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -101,65 +134,90 @@ func (s *ApiServer) deletePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.rpo.DeletePost(r.Context(), id)
+	post, err := s.rpo.GetPost(r.Context(), id)
+	if err == nil {
+		err = s.rpo.DeletePost(r.Context(), id)
+	}
 	if err != nil {
 		if err == repo.ErrPostNotFound {
 			http.Error(w, "Post not found", http.StatusNotFound)
 			return
 		}
-		s.logger.Errorw("error deleting post", "error", err)
+		s.log(r).Errorw("error deleting post", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	s.hooks.EmitPost(r.Context(), webhook.EventPostDeleted, post, nil)
 	w.WriteHeader(http.StatusNoContent)
-	s.logger.Infow("post deleted", "id", id)
+	s.log(r).Infow("post deleted", "id", id)
 }
 
 // makePostHandler godoc
-// @Summary Set a post as posted
-// @Description Set a post as posted
+// @Summary Publish the next post
+// @Description Publish the most overdue pinned post, or else the post at the front of the queue, and mark it as posted, or as failed if publishing fails. When alt text is required, a post with a photo that has no alt text fails with a 422. Nothing is published while a blackout is in effect; the 409 response names the blackout and when publishing resumes. Concurrent calls never publish the same post twice; the caller that loses the race gets a 409. If the post script runs past the publish timeout it is killed, the post is marked as interrupted for manual review, and the response is 504. Feed posts and stories have separate queues; the queue parameter picks which one to publish from
 // @Tags posts
+// @Param queue query string false "Queue to publish from: feed (the default) or story"
 // @Router /api/posts/make_post [post]
 // @Security Bearer
 // @Success 204
 func (s *ApiServer) makePostHandler(w http.ResponseWriter, r *http.Request) {
-	post, err := s.rpo.GetPostToPost(r.Context())
+	queue, _, ok := s.queue(w, r)
+	if !ok {
+		return
+	}
+	post, err := s.pub.PublishNext(r.Context(), queue)
 	if err != nil {
 		if err == repo.ErrPostNotFound {
 			http.Error(w, "Nothing to post", http.StatusNotFound)
 			return
 		}
-		s.logger.Errorw("error getting post to post", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-
-	err = instagram.ExecutePost(r.Context(), s.logger, s.rpo, s.instaWorkingDir, s.instaUsername, s.instaPassword, post)
-	if err != nil {
-		s.logger.Errorw("error executing post", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-
-	err = s.rpo.SetIsPostedValueOfPost(r.Context(), post.ID, true)
-	if err != nil {
-		if err == repo.ErrPostNotFound {
-			http.Error(w, "Post not found", http.StatusNotFound)
+		if errors.Is(err, publisher.ErrBlackout) {
+			s.log(r).Infow("not publishing during blackout", "reason", err)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, repo.ErrPostClaimed) {
+			s.log(r).Infow("post is already being published", "reason", err)
+			http.Error(w, "Post is already being published", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, publisher.ErrShuttingDown) {
+			http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, instagram.ErrTimeout) {
+			s.log(r).Errorw("publish timed out", "error", err)
+			http.Error(w, "Publish timed out; the post is marked as interrupted, check the account", http.StatusGatewayTimeout)
 			return
 		}
-		s.logger.Errorw("error setting post as posted", "error", err)
+		if errors.Is(err, instagram.ErrCancelled) {
+			s.log(r).Warnw("publish cancelled", "error", err)
+			http.Error(w, "Publish cancelled; the post is marked as interrupted, check the account", http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, caption.ErrInvalidCaption) {
+			s.log(r).Errorw("refusing to publish post with invalid caption", "error", err)
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if errors.Is(err, repo.ErrMissingAltText) {
+			s.log(r).Errorw("refusing to publish post without alt text", "error", err)
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		s.log(r).Errorw("error publishing post", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
-	s.logger.Infow("post set as posted", "id", post.ID)
+	s.log(r).Infow("post set as posted", "id", post.ID)
 }
 
 // setPostAsUnpostedHandler godoc
 // @Summary Set a post as unposted
-// @Description Set a post as unposted
+// @Description Move a posted post back to the end of the queue. When alt text is required, a post with a photo that has no alt text cannot be queued
 // @Tags posts
 // @Param id path int true "Post ID"
 // @Router /api/posts/{id}/unpost [post]
@@ -178,19 +236,24 @@ func (s *ApiServer) setPostAsUnpostedHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = s.rpo.SetIsPostedValueOfPost(r.Context(), id, false)
+	err = s.rpo.SetPostStatus(r.Context(), id, repo.StatusQueued)
 	if err != nil {
 		if err == repo.ErrPostNotFound {
 			http.Error(w, "Post not found", http.StatusNotFound)
 			return
 		}
-		s.logger.Errorw("error setting post as unposted", "error", err)
+		if errors.Is(err, repo.ErrInvalidTransition) || errors.Is(err, repo.ErrMissingAltText) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		s.log(r).Errorw("error setting post as unposted", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	s.hooks.EmitPostByID(r.Context(), webhook.EventPostUnposted, id)
 	w.WriteHeader(http.StatusNoContent)
-	s.logger.Infow("post set as unposted", "id", id)
+	s.log(r).Infow("post set as unposted", "id", id)
 }
 
 // cleanPositionsHandler godoc
@@ -203,11 +266,286 @@ func (s *ApiServer) setPostAsUnpostedHandler(w http.ResponseWriter, r *http.Requ
 func (s *ApiServer) cleanPositionsHandler(w http.ResponseWriter, r *http.Request) {
 	err := s.rpo.CleanPositions(r.Context())
 	if err != nil {
-		s.logger.Errorw("error cleaning post positions", "error", err)
+		s.log(r).Errorw("error cleaning post positions", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, queue := range repo.AllQueues {
+		s.hooks.EmitQueueReordered(r.Context(), queue)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("post positions cleaned")
+}
+
+type lintCaptionRequest struct {
+	Caption       string  `json:"caption"`
+	HashtagSetIDs []int64 `json:"hashtag_set_ids"`
+	PostID        int64   `json:"post_id"`
+}
+
+// lintCaptionHandler godoc
+// @Summary Lint a caption
+// @Description Check a caption against Instagram's length, hashtag and mention limits, with hashtag sets appended as they are when the post is published. The sets are those in hashtag_set_ids, or else those attached to the post post_id
+// @Tags captions
+// @Accept json
+// @Produce json
+// @Param request body lintCaptionRequest true "Caption to lint"
+// @Router /api/captions/lint [post]
+// @Success 200 {object} caption.Result
+func (s *ApiServer) lintCaptionHandler(w http.ResponseWriter, r *http.Request) {
+	var req lintCaptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var sets []repo.HashtagSet
+	var err error
+	switch {
+	case req.HashtagSetIDs != nil:
+		sets, err = s.rpo.GetHashtagSets(r.Context(), req.HashtagSetIDs)
+	case req.PostID != 0:
+		if _, err = s.rpo.GetPost(r.Context(), req.PostID); err == nil {
+			sets, err = s.rpo.GetHashtagSetsForPost(r.Context(), req.PostID)
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrHashtagSetNotFound):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repo.ErrPostNotFound):
+			http.Error(w, "Post not found", http.StatusNotFound)
+		default:
+			s.log(r).Errorw("error getting hashtag sets", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp, err := json.MarshalIndent(caption.Lint(repo.AppendHashtagSets(req.Caption, sets)), "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling lint result", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+type postStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// setPostStatusHandler godoc
+// @Summary Change a post's status
+// @Description Move a post through its lifecycle: draft, queued, posted, failed, interrupted, archived. Posts only enter and leave publishing through the publisher, so moving a post into or out of it is a conflict. When alt text is required, a post with a photo that has no alt text cannot be queued
+// @Tags posts
+// @Accept json
+// @Param id path int true "Post ID"
+// @Param request body postStatusRequest true "New status"
+// @Router /api/posts/{id}/status [post]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) setPostStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req postStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	status, err := repo.ParseStatus(req.Status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.SetPostStatus(r.Context(), id, status)
+	if err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repo.ErrInvalidTransition) || errors.Is(err, repo.ErrMissingAltText) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		s.log(r).Errorw("error changing post status", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.hooks.EmitPostByID(r.Context(), webhook.EventPostStatusChanged, id)
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("post status changed", "id", id, "status", status)
+}
+
+type pinPostRequest struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+// pinPostHandler godoc
+// @Summary Pin a post to a publish time
+// @Description Take a post out of the ordered queue and publish it at an exact time (RFC 3339)
+// @Tags posts
+// @Accept json
+// @Param id path int true "Post ID"
+// @Param request body pinPostRequest true "Publish time"
+// @Router /api/posts/{id}/schedule [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) pinPostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req pinPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.PinPost(r.Context(), id, req.ScheduledAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrPostNotFound):
+			http.Error(w, "Post not found", http.StatusNotFound)
+		case errors.Is(err, repo.ErrScheduleInThePast):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repo.ErrInvalidTransition):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			s.log(r).Errorw("error pinning post", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.hooks.EmitQueueReorderedForPost(r.Context(), id)
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("post pinned", "id", id, "scheduled_at", req.ScheduledAt)
+}
+
+// unpinPostHandler godoc
+// @Summary Unpin a post
+// @Description Remove a post's publish time, returning it to the end of the ordered queue
+// @Tags posts
+// @Param id path int true "Post ID"
+// @Router /api/posts/{id}/schedule [delete]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) unpinPostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.UnpinPost(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		s.log(r).Errorw("error unpinning post", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.hooks.EmitQueueReorderedForPost(r.Context(), id)
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("post unpinned", "id", id)
+}
+
+type firstCommentRequest struct {
+	FirstComment string `json:"first_comment"`
+}
+
+// setFirstCommentHandler godoc
+// @Summary Set a post's first comment
+// @Description Set the comment posted on a post right after it is published, such as its hashtags. An empty comment means none is posted. The comment is held to the same limits as a caption
+// @Tags posts
+// @Accept json
+// @Param id path int true "Post ID"
+// @Param request body firstCommentRequest true "First comment"
+// @Router /api/posts/{id}/first_comment [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) setFirstCommentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req firstCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	comment := strings.TrimSpace(req.FirstComment)
+	if res := caption.Lint(comment); comment != "" && !res.Valid() {
+		http.Error(w, "Invalid first comment: "+res.Err().Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.UpdatePostFirstComment(r.Context(), id, comment)
+	if err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		s.log(r).Errorw("error updating post first comment", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("post first comment updated", "id", id)
+}
+
+// retryCommentHandler godoc
+// @Summary Retry a post's first comment
+// @Description Post the first comment of a published post whose comment failed. The result is recorded on the post; if the comment fails again the response is 502 with the reason
+// @Tags posts
+// @Param id path int true "Post ID"
+// @Router /api/posts/{id}/comment/retry [post]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) retryCommentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.pub.RetryComment(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrPostNotFound):
+			http.Error(w, "Post not found", http.StatusNotFound)
+		case errors.Is(err, publisher.ErrNoComment):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, repo.ErrPostClaimed):
+			http.Error(w, "Post is already being published or commented on", http.StatusConflict)
+		case errors.Is(err, publisher.ErrShuttingDown):
+			http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+		default:
+			s.log(r).Errorw("error retrying first comment", "error", err)
+			http.Error(w, "Comment failed: "+err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
-	s.logger.Infow("post positions cleaned")
+	s.log(r).Infow("first comment posted", "id", id)
 }
@@ -0,0 +1,340 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/btschwartz12/isza/caption"
+	"github.com/btschwartz12/isza/repo"
+	"github.com/go-chi/chi/v5"
+)
+
+type hashtagSetRequest struct {
+	Name     string   `json:"name"`
+	Hashtags []string `json:"hashtags"`
+}
+
+type postHashtagSetsRequest struct {
+	HashtagSetIDs []int64 `json:"hashtag_set_ids"`
+}
+
+// getAllHashtagSetsHandler godoc
+// @Summary Get all hashtag sets
+// @Description Get all hashtag sets
+// @Tags hashtags
+// @Produce json
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/hashtag_sets [get]
+// @Success 200
+func (s *ApiServer) getAllHashtagSetsHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	sets, err := s.rpo.GetAllHashtagSets(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting all hashtag sets", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	for i := range sets {
+		sets[i] = sets[i].In(loc)
+	}
+
+	resp, err := json.MarshalIndent(sets, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling hashtag sets", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// getHashtagSetHandler godoc
+// @Summary Get a hashtag set
+// @Description Get a hashtag set
+// @Tags hashtags
+// @Produce json
+// @Param id path int true "Hashtag set ID"
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/hashtag_sets/{id} [get]
+// @Success 200
+func (s *ApiServer) getHashtagSetHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid hashtag set ID", http.StatusBadRequest)
+		return
+	}
+
+	set, err := s.rpo.GetHashtagSet(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repo.ErrHashtagSetNotFound) {
+			http.Error(w, "Hashtag set not found", http.StatusNotFound)
+			return
+		}
+		s.log(r).Errorw("error getting hashtag set", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.MarshalIndent(set.In(loc), "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling hashtag set", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// createHashtagSetHandler godoc
+// @Summary Create a hashtag set
+// @Description Create a named hashtag set that can be appended to posts
+// @Tags hashtags
+// @Accept json
+// @Produce json
+// @Param request body hashtagSetRequest true "Hashtag set"
+// @Router /api/hashtag_sets [post]
+// @Security Bearer
+// @Success 201
+func (s *ApiServer) createHashtagSetHandler(w http.ResponseWriter, r *http.Request) {
+	name, hashtags, ok := decodeHashtagSetRequest(w, r)
+	if !ok {
+		return
+	}
+
+	set, err := s.rpo.InsertHashtagSet(r.Context(), name, hashtags)
+	if err != nil {
+		if !writeHashtagSetError(w, err) {
+			s.log(r).Errorw("error creating hashtag set", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp, err := json.MarshalIndent(set, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling hashtag set", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+	s.log(r).Infow("hashtag set created", "id", set.ID)
+}
+
+// updateHashtagSetHandler godoc
+// @Summary Update a hashtag set
+// @Description Update a hashtag set; posts referencing it pick up the change when published
+// @Tags hashtags
+// @Accept json
+// @Param id path int true "Hashtag set ID"
+// @Param request body hashtagSetRequest true "Hashtag set"
+// @Router /api/hashtag_sets/{id} [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) updateHashtagSetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid hashtag set ID", http.StatusBadRequest)
+		return
+	}
+
+	name, hashtags, ok := decodeHashtagSetRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err = s.rpo.UpdateHashtagSet(r.Context(), id, name, hashtags)
+	if err != nil {
+		if !writeHashtagSetError(w, err) {
+			s.log(r).Errorw("error updating hashtag set", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("hashtag set updated", "id", id)
+}
+
+// deleteHashtagSetHandler godoc
+// @Summary Delete a hashtag set
+// @Description Delete a hashtag set and unlink it from all posts
+// @Tags hashtags
+// @Param id path int true "Hashtag set ID"
+// @Router /api/hashtag_sets/{id} [delete]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) deleteHashtagSetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid hashtag set ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.DeleteHashtagSet(r.Context(), id)
+	if err != nil {
+		if !writeHashtagSetError(w, err) {
+			s.log(r).Errorw("error deleting hashtag set", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("hashtag set deleted", "id", id)
+}
+
+// getPostHashtagSetsHandler godoc
+// @Summary Get a post's hashtag sets
+// @Description Get the hashtag sets appended to a post when it is published
+// @Tags hashtags
+// @Produce json
+// @Param id path int true "Post ID"
+// @Router /api/posts/{id}/hashtag_sets [get]
+// @Success 200
+func (s *ApiServer) getPostHashtagSetsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.rpo.GetPost(r.Context(), id); err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		s.log(r).Errorw("error getting post", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	sets, err := s.rpo.GetHashtagSetsForPost(r.Context(), id)
+	if err != nil {
+		s.log(r).Errorw("error getting post hashtag sets", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.MarshalIndent(sets, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling hashtag sets", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// setPostHashtagSetsHandler godoc
+// @Summary Set a post's hashtag sets
+// @Description Replace the hashtag sets appended to a post when it is published
+// @Tags hashtags
+// @Accept json
+// @Param id path int true "Post ID"
+// @Param request body postHashtagSetsRequest true "Hashtag set IDs"
+// @Router /api/posts/{id}/hashtag_sets [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) setPostHashtagSetsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req postHashtagSetsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.SetPostHashtagSets(r.Context(), id, req.HashtagSetIDs)
+	if err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if !writeHashtagSetError(w, err) {
+			s.log(r).Errorw("error setting post hashtag sets", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("post hashtag sets updated", "id", id, "hashtag_set_ids", req.HashtagSetIDs)
+}
+
+// getHashtagUsageHandler godoc
+// @Summary Get hashtag usage
+// @Description Get how often each hashtag was used across posted content
+// @Tags hashtags
+// @Produce json
+// @Router /api/hashtags/usage [get]
+// @Success 200
+func (s *ApiServer) getHashtagUsageHandler(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.rpo.GetHashtagUsage(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting hashtag usage", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.MarshalIndent(usage, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling hashtag usage", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func decodeHashtagSetRequest(w http.ResponseWriter, r *http.Request) (string, []string, bool) {
+	var req hashtagSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return "", nil, false
+	}
+	hashtags, err := caption.ParseHashtags(strings.Join(req.Hashtags, " "))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", nil, false
+	}
+	return strings.TrimSpace(req.Name), hashtags, true
+}
+
+// writeHashtagSetError writes the response for well-known hashtag set errors
+// and reports whether it did so.
+func writeHashtagSetError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, repo.ErrHashtagSetNotFound):
+		http.Error(w, "Hashtag set not found", http.StatusNotFound)
+	case errors.Is(err, repo.ErrHashtagSetExists):
+		http.Error(w, "Hashtag set already exists", http.StatusConflict)
+	case errors.Is(err, repo.ErrInvalidHashtagSet):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		return false
+	}
+	return true
+}
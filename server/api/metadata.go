@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samber/mo"
+
+	"github.com/btschwartz12/isza/repo"
+)
+
+type userTagRequest struct {
+	Username string  `json:"username"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+}
+
+type imageRequest struct {
+	Filename string           `json:"filename"`
+	AltText  string           `json:"alt_text"`
+	UserTags []userTagRequest `json:"user_tags"`
+}
+
+type postImagesRequest struct {
+	Images []imageRequest `json:"images"`
+}
+
+type locationRequest struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
+}
+
+// getPostImagesHandler godoc
+// @Summary Get a post's image metadata
+// @Description Get the alt text and user tags of each file of a post, in order
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Router /api/posts/{id}/images [get]
+// @Success 200
+func (s *ApiServer) getPostImagesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	images, err := s.rpo.GetPostImages(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		s.log(r).Errorw("error getting post images", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.MarshalIndent(images, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling post images", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// setPostImagesHandler godoc
+// @Summary Set a post's image metadata
+// @Description Replace the alt text and user tags of the files of a post. Each image names one of the post's files; files left out get no metadata. User tag positions are fractions of the width and height from the top left corner
+// @Tags posts
+// @Accept json
+// @Param id path int true "Post ID"
+// @Param request body postImagesRequest true "Image metadata"
+// @Router /api/posts/{id}/images [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) setPostImagesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req postImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	images := make([]repo.Image, len(req.Images))
+	for i, image := range req.Images {
+		tags := make([]repo.UserTag, len(image.UserTags))
+		for j, tag := range image.UserTags {
+			tags[j] = repo.UserTag{Username: tag.Username, X: tag.X, Y: tag.Y}
+		}
+		images[i] = repo.Image{Filename: image.Filename, AltText: image.AltText, UserTags: tags}
+	}
+
+	err = s.rpo.SetPostImages(r.Context(), id, images)
+	if err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repo.ErrInvalidMetadata) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.log(r).Errorw("error setting post images", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("post images updated", "id", id)
+}
+
+// setPostLocationHandler godoc
+// @Summary Set a post's location
+// @Description Tag a post with a location
+// @Tags posts
+// @Accept json
+// @Param id path int true "Post ID"
+// @Param request body locationRequest true "Location"
+// @Router /api/posts/{id}/location [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) setPostLocationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req locationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	loc := mo.Some(repo.Location{Name: req.Name, Lat: req.Lat, Lng: req.Lng})
+	s.writeSetLocation(w, r, id, loc)
+}
+
+// deletePostLocationHandler godoc
+// @Summary Remove a post's location
+// @Description Remove the location a post is tagged with
+// @Tags posts
+// @Param id path int true "Post ID"
+// @Router /api/posts/{id}/location [delete]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) deletePostLocationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	s.writeSetLocation(w, r, id, mo.None[repo.Location]())
+}
+
+func (s *ApiServer) writeSetLocation(w http.ResponseWriter, r *http.Request, id int64, loc mo.Option[repo.Location]) {
+	err := s.rpo.SetPostLocation(r.Context(), id, loc)
+	if err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repo.ErrInvalidMetadata) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.log(r).Errorw("error setting post location", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("post location updated", "id", id)
+}
@@ -1,9 +1,20 @@
 package api
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/logging"
 )
 
+// log returns the logger of the request, which carries its ID.
+func (s *ApiServer) log(r *http.Request) *zap.SugaredLogger {
+	return logging.FromContext(r.Context(), s.logger)
+}
+
 func (s *ApiServer) tokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := r.Header.Get("Authorization")
@@ -16,7 +27,31 @@ func (s *ApiServer) tokenMiddleware(next http.Handler) http.Handler {
 			token = r.URL.Query().Get("token")
 		}
 		if token != s.token {
-			s.logger.Infow("unauthorized", "token", token)
+			s.log(r).Infow("unauthorized", "token", token)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// calendarTokenMiddleware lets through requests carrying the calendar token,
+// in the token query parameter as calendar apps send it or in the
+// Authorization header. The calendar token only reads the feed, so it can be
+// handed to calendar apps in place of the API token. Without one the feed is
+// off.
+func (s *ApiServer) calendarTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.calendarToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.calendarToken)) != 1 {
+			s.log(r).Infow("unauthorized calendar feed request")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
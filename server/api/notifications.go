@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/btschwartz12/isza/notify"
+)
+
+// sendTestNotificationHandler godoc
+// @Summary Send a test email
+// @Description Send a test email to the notification recipients, to check the SMTP settings
+// @Tags notifications
+// @Router /api/notifications/test [post]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) sendTestNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.notifier.SendTest(r.Context())
+	if err != nil {
+		s.writeNotificationError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("test notification sent")
+}
+
+// sendDailySummaryHandler godoc
+// @Summary Send the daily summary
+// @Description Send the daily summary email now, covering the last and next 24 hours
+// @Tags notifications
+// @Router /api/notifications/daily_summary [post]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) sendDailySummaryHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.notifier.SendDailySummary(r.Context(), time.Now())
+	if err != nil {
+		s.writeNotificationError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("daily summary sent")
+}
+
+func (s *ApiServer) writeNotificationError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, notify.ErrInvalidConfig) {
+		http.Error(w, "Email notifications are not configured", http.StatusConflict)
+		return
+	}
+	s.log(r).Errorw("error sending notification", "error", err)
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
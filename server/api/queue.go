@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
+)
+
+type projectedPost struct {
+	PostID    int64     `json:"post_id"`
+	Position  int64     `json:"position"`
+	Pinned    bool      `json:"pinned"`
+	PublishAt time.Time `json:"publish_at"`
+	Label     string    `json:"label"`
+}
+
+type queueProjectionResponse struct {
+	Queue     repo.Queue `json:"queue"`
+	PostTimes string     `json:"post_times"`
+	// TimeZone is the instance time zone the post times are in.
+	TimeZone string `json:"time_zone"`
+	// DisplayTimeZone is the time zone of publish times and labels.
+	DisplayTimeZone string          `json:"display_time_zone"`
+	Posts           []projectedPost `json:"posts"`
+}
+
+// getQueueProjectionHandler godoc
+// @Summary Get the queue projection
+// @Description Get the expected publish time of every post in a queue, based on the queue's posting schedule and pinned times
+// @Tags queue
+// @Produce json
+// @Param queue query string false "Queue to project: feed (the default) or story"
+// @Param tz query string false "IANA time zone to show times in, defaults to the instance time zone"
+// @Router /api/queue/projection [get]
+// @Success 200 {object} queueProjectionResponse
+func (s *ApiServer) getQueueProjectionHandler(w http.ResponseWriter, r *http.Request) {
+	loc, ok := s.displayLocation(w, r)
+	if !ok {
+		return
+	}
+	queue, sched, ok := s.queue(w, r)
+	if !ok {
+		return
+	}
+
+	projections, err := schedule.ProjectQueue(r.Context(), s.rpo, queue, sched, time.Now().In(loc))
+	if err != nil {
+		s.log(r).Errorw("error projecting queue", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	posts := make([]projectedPost, len(projections))
+	for i, p := range projections {
+		posts[i] = projectedPost{
+			PostID:    p.Post.ID,
+			Position:  p.Post.Position,
+			Pinned:    p.Pinned,
+			PublishAt: p.PublishAt,
+			Label:     p.Label,
+		}
+	}
+
+	resp, err := json.MarshalIndent(queueProjectionResponse{
+		Queue:           queue,
+		PostTimes:       sched.String(),
+		TimeZone:        sched.Location().String(),
+		DisplayTimeZone: loc.String(),
+		Posts:           posts,
+	}, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling queue projection", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// getQueueStatusHandler godoc
+// @Summary Get the queue status
+// @Description Get how many posts are queued, how far they reach at the posting schedule, and whether the queue is below the running-low threshold
+// @Tags queue
+// @Produce json
+// @Router /api/queue/status [get]
+// @Success 200 {object} alert.QueueStatus
+func (s *ApiServer) getQueueStatusHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := s.monitor.Status(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting queue status", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.MarshalIndent(status, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling queue status", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
@@ -3,63 +3,132 @@ package api
 import (
 	"fmt"
 	"net/http"
-	"path/filepath"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 	"go.uber.org/zap"
 
+	"github.com/btschwartz12/isza/alert"
+	"github.com/btschwartz12/isza/idempotency"
+	"github.com/btschwartz12/isza/notify"
+	"github.com/btschwartz12/isza/publisher"
 	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
 	"github.com/btschwartz12/isza/server/api/swagger"
+	"github.com/btschwartz12/isza/webhook"
 )
 
 type ApiServer struct {
-	router          *chi.Mux
-	logger          *zap.SugaredLogger
-	rpo             *repo.Repo
-	token           string
-	instaUsername   string
-	instaPassword   string
-	instaWorkingDir string
+	router        *chi.Mux
+	logger        *zap.SugaredLogger
+	rpo           *repo.Repo
+	pub           *publisher.Publisher
+	sched         *schedule.Schedule
+	storySched    *schedule.Schedule
+	hooks         *webhook.Dispatcher
+	monitor       *alert.Monitor
+	notifier      *notify.Notifier
+	token         string
+	calendarToken string
+	instaUsername string
+	maxUploadSize int64
 }
 
-func (s *ApiServer) Init(
-	logger *zap.SugaredLogger,
-	rpo *repo.Repo,
-	prefix,
-	authToken,
-	instaUsername,
-	instaPassword,
-	instaWorkingDir string,
-) error {
+// Config is what the API server is built from.
+type Config struct {
+	Repo      *repo.Repo
+	Publisher *publisher.Publisher
+	// Schedule and StorySchedule are the posting schedules of the feed and
+	// story queues.
+	Schedule      *schedule.Schedule
+	StorySchedule *schedule.Schedule
+	Hooks         *webhook.Dispatcher
+	Monitor       *alert.Monitor
+	Notifier      *notify.Notifier
+	// Prefix is the path the API is mounted at.
+	Prefix    string
+	AuthToken string
+	// CalendarToken is the token that reads the calendar feed, or empty to
+	// turn the feed off.
+	CalendarToken     string
+	IdempotencyWindow time.Duration
+	// MaxUploadSize is the most a request may upload, in bytes.
+	MaxUploadSize int64
+}
+
+func (s *ApiServer) Init(logger *zap.SugaredLogger, cfg Config) error {
 	s.logger = logger
 	s.router = chi.NewRouter()
-	s.rpo = rpo
-	s.token = authToken
-	s.instaUsername = instaUsername
-	s.instaPassword = instaPassword
-	
-	instaAbsDir, err := filepath.Abs(instaWorkingDir)
-	if err != nil {
-		return fmt.Errorf("error getting absolute path for instagram working directory: %w", err)
-	}
-	s.instaWorkingDir = instaAbsDir
+	s.rpo = cfg.Repo
+	s.pub = cfg.Publisher
+	s.sched = cfg.Schedule
+	s.storySched = cfg.StorySchedule
+	s.hooks = cfg.Hooks
+	s.monitor = cfg.Monitor
+	s.notifier = cfg.Notifier
+	s.token = cfg.AuthToken
+	s.calendarToken = cfg.CalendarToken
+	s.instaUsername = cfg.Publisher.Username()
+	s.maxUploadSize = cfg.MaxUploadSize
 
-	s.router.Get("/", http.RedirectHandler(fmt.Sprintf("%s/swagger/index.html", prefix), http.StatusMovedPermanently).ServeHTTP)
+	s.router.Get("/", http.RedirectHandler(fmt.Sprintf("%s/swagger/index.html", cfg.Prefix), http.StatusMovedPermanently).ServeHTTP)
 	s.router.Get("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(swagger.SwaggerJSON)
 	})
-	s.router.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(fmt.Sprintf("%s/swagger.json", prefix))))
+	s.router.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(fmt.Sprintf("%s/swagger.json", cfg.Prefix))))
 
 	s.router.Get("/posts", s.getAllPostsHandler)
 	s.router.Get("/posts/{id}", s.getPostHandler)
+	s.router.Post("/captions/lint", s.lintCaptionHandler)
+	s.router.Post("/captions/preview", s.previewCaptionHandler)
+	s.router.Get("/caption_variables", s.getCaptionVariablesHandler)
+	s.router.Get("/hashtag_sets", s.getAllHashtagSetsHandler)
+	s.router.Get("/hashtag_sets/{id}", s.getHashtagSetHandler)
+	s.router.Get("/posts/{id}/hashtag_sets", s.getPostHashtagSetsHandler)
+	s.router.Get("/posts/{id}/images", s.getPostImagesHandler)
+	s.router.Get("/hashtags/usage", s.getHashtagUsageHandler)
+	s.router.Get("/queue/projection", s.getQueueProjectionHandler)
+	s.router.Get("/queue/status", s.getQueueStatusHandler)
+	s.router.Get("/blackouts", s.getAllBlackoutsHandler)
+	s.router.Get("/blackouts/{id}", s.getBlackoutHandler)
+	s.router.With(s.calendarTokenMiddleware).Get("/calendar.ics", s.calendarFeedHandler)
 	s.router.Group(func(rr chi.Router) {
 		rr.Use(s.tokenMiddleware)
+		rr.Use(idempotency.Middleware(logger, cfg.Repo, cfg.IdempotencyWindow, cfg.MaxUploadSize))
 		rr.Delete("/posts/{id}", s.deletePostHandler)
 		rr.Post("/posts/make_post", s.makePostHandler)
 		rr.Post("/posts/{id}/unpost", s.setPostAsUnpostedHandler)
+		rr.Post("/posts/{id}/status", s.setPostStatusHandler)
+		rr.Put("/posts/{id}/schedule", s.pinPostHandler)
+		rr.Delete("/posts/{id}/schedule", s.unpinPostHandler)
 		rr.Post("/posts/clean_positions", s.cleanPositionsHandler)
+		rr.Put("/posts/{id}/hashtag_sets", s.setPostHashtagSetsHandler)
+		rr.Put("/posts/{id}/images", s.setPostImagesHandler)
+		rr.Put("/posts/{id}/location", s.setPostLocationHandler)
+		rr.Delete("/posts/{id}/location", s.deletePostLocationHandler)
+		rr.Post("/posts/{id}/files", s.addPostFilesHandler)
+		rr.Put("/posts/{id}/files", s.reorderPostFilesHandler)
+		rr.Delete("/posts/{id}/files/{filename}", s.deletePostFileHandler)
+		rr.Put("/posts/{id}/first_comment", s.setFirstCommentHandler)
+		rr.Post("/posts/{id}/comment/retry", s.retryCommentHandler)
+		rr.Post("/hashtag_sets", s.createHashtagSetHandler)
+		rr.Put("/hashtag_sets/{id}", s.updateHashtagSetHandler)
+		rr.Delete("/hashtag_sets/{id}", s.deleteHashtagSetHandler)
+		rr.Put("/caption_variables/{key}", s.setCaptionVariableHandler)
+		rr.Delete("/caption_variables/{key}", s.deleteCaptionVariableHandler)
+		rr.Post("/blackouts", s.createBlackoutHandler)
+		rr.Put("/blackouts/{id}", s.updateBlackoutHandler)
+		rr.Delete("/blackouts/{id}", s.deleteBlackoutHandler)
+		rr.Get("/webhooks", s.getAllWebhooksHandler)
+		rr.Post("/webhooks", s.createWebhookHandler)
+		rr.Get("/webhooks/{id}", s.getWebhookHandler)
+		rr.Put("/webhooks/{id}", s.updateWebhookHandler)
+		rr.Delete("/webhooks/{id}", s.deleteWebhookHandler)
+		rr.Get("/webhooks/{id}/deliveries", s.getWebhookDeliveriesHandler)
+		rr.Post("/notifications/test", s.sendTestNotificationHandler)
+		rr.Post("/notifications/daily_summary", s.sendDailySummaryHandler)
 	})
 
 	return nil
@@ -68,3 +137,38 @@ func (s *ApiServer) Init(
 func (s *ApiServer) GetRouter() chi.Router {
 	return s.router
 }
+
+// displayLocation returns the time zone named by the tz query parameter, or
+// the instance time zone if there is none. It writes a 400 response and
+// returns false if the name is not a valid IANA time zone.
+func (s *ApiServer) displayLocation(w http.ResponseWriter, r *http.Request) (*time.Location, bool) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		return s.sched.Location(), true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		http.Error(w, "Invalid time zone", http.StatusBadRequest)
+		return nil, false
+	}
+	return loc, true
+}
+
+// queue returns the queue named by the queue query parameter, the feed queue
+// if it is empty, and the posting schedule of the queue. It writes a 400 and
+// returns false if the name is invalid.
+func (s *ApiServer) queue(w http.ResponseWriter, r *http.Request) (repo.Queue, *schedule.Schedule, bool) {
+	name := r.URL.Query().Get("queue")
+	if name == "" {
+		return repo.QueueFeed, s.sched, true
+	}
+	queue, err := repo.ParseQueue(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", nil, false
+	}
+	if queue == repo.QueueStory {
+		return queue, s.storySched, true
+	}
+	return queue, s.sched, true
+}
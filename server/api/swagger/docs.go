@@ -15,35 +15,1202 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/blackouts": {
+            "get": {
+                "description": "Get all blackout periods during which nothing is published automatically",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blackouts"
+                ],
+                "summary": "Get all blackouts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Create a blackout period (RFC 3339 times). A weekly or yearly blackout repeats its first occurrence on the wall clock",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blackouts"
+                ],
+                "summary": "Create a blackout",
+                "parameters": [
+                    {
+                        "description": "Blackout",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.blackoutRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        },
+        "/api/blackouts/{id}": {
+            "get": {
+                "description": "Get a blackout",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blackouts"
+                ],
+                "summary": "Get a blackout",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Blackout ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Update a blackout period (RFC 3339 times)",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blackouts"
+                ],
+                "summary": "Update a blackout",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Blackout ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Blackout",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.blackoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete a blackout period",
+                "tags": [
+                    "blackouts"
+                ],
+                "summary": "Delete a blackout",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Blackout ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/calendar.ics": {
+            "get": {
+                "description": "iCalendar feed of queued posts at their projected publish slots and posted posts at the time they went out. It takes the calendar token, not the API token, which calendar apps can pass as a query parameter. The feed is off, and 404s, unless a calendar token is set.",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Get the posting calendar feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Calendar token",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/caption_variables": {
+            "get": {
+                "description": "Get the account's custom caption template variables",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "captions"
+                ],
+                "summary": "Get caption variables",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/caption_variables/{key}": {
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Create or update a custom caption template variable, referenced in captions as {{key}}",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "captions"
+                ],
+                "summary": "Set a caption variable",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Variable name",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variable value",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.captionVariableRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete a custom caption template variable",
+                "tags": [
+                    "captions"
+                ],
+                "summary": "Delete a caption variable",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Variable name",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/captions/lint": {
+            "post": {
+                "description": "Check a caption against Instagram's length, hashtag and mention limits, with hashtag sets appended as they are when the post is published. The sets are those in hashtag_set_ids, or else those attached to the post post_id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "captions"
+                ],
+                "summary": "Lint a caption",
+                "parameters": [
+                    {
+                        "description": "Caption to lint",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.lintCaptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/caption.Result"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/captions/preview": {
+            "post": {
+                "description": "Render a caption template as it would be published now, with the post's hashtag sets appended",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "captions"
+                ],
+                "summary": "Preview a caption",
+                "parameters": [
+                    {
+                        "description": "Caption template and optional post ID",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.previewCaptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.previewCaptionResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hashtag_sets": {
+            "get": {
+                "description": "Get all hashtag sets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hashtags"
+                ],
+                "summary": "Get all hashtag sets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Create a named hashtag set that can be appended to posts",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hashtags"
+                ],
+                "summary": "Create a hashtag set",
+                "parameters": [
+                    {
+                        "description": "Hashtag set",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.hashtagSetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        },
+        "/api/hashtag_sets/{id}": {
+            "get": {
+                "description": "Get a hashtag set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hashtags"
+                ],
+                "summary": "Get a hashtag set",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Hashtag set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Update a hashtag set; posts referencing it pick up the change when published",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hashtags"
+                ],
+                "summary": "Update a hashtag set",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Hashtag set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Hashtag set",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.hashtagSetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete a hashtag set and unlink it from all posts",
+                "tags": [
+                    "hashtags"
+                ],
+                "summary": "Delete a hashtag set",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Hashtag set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/hashtags/usage": {
+            "get": {
+                "description": "Get how often each hashtag was used across posted content",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hashtags"
+                ],
+                "summary": "Get hashtag usage",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/notifications/daily_summary": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Send the daily summary email now, covering the last and next 24 hours",
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Send the daily summary",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/notifications/test": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Send a test email to the notification recipients, to check the SMTP settings",
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Send a test email",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
         "/api/posts": {
             "get": {
-                "description": "Get all posts",
+                "description": "Get all posts, optionally filtered by status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Get all posts",
+                "parameters": [
+                    {
+                        "enum": [
+                            "draft",
+                            "queued",
+                            "publishing",
+                            "posted",
+                            "failed",
+                            "interrupted",
+                            "archived"
+                        ],
+                        "type": "string",
+                        "description": "Post status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/posts/clean_positions": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Clean post positions",
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Clean post positions",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/posts/make_post": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Publish the most overdue pinned post, or else the post at the front of the queue, and mark it as posted, or as failed if publishing fails. When alt text is required, a post with a photo that has no alt text fails with a 422. Nothing is published while a blackout is in effect; the 409 response names the blackout and when publishing resumes. Concurrent calls never publish the same post twice; the caller that loses the race gets a 409. If the post script runs past the publish timeout it is killed, the post is marked as interrupted for manual review, and the response is 504. Feed posts and stories have separate queues; the queue parameter picks which one to publish from",
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Publish the next post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Queue to publish from: feed (the default) or story",
+                        "name": "queue",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}": {
+            "get": {
+                "description": "Get a post",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Get a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete a post",
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Delete a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}/comment/retry": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Post the first comment of a published post whose comment failed. The result is recorded on the post; if the comment fails again the response is 502 with the reason",
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Retry a post's first comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}/files": {
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Put the files of a draft, queued or failed post in a new order, which must list each of them once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Reorder the files of a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Filenames in their new order",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.reorderFilesRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Append files to a draft, queued or failed post, after its existing files. A feed post becomes a carousel. When alt text is required, a queued post must be left with alt text on every photo, or the response is 422",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Add files to a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to add; repeat the field to add several",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Alt text of the file; repeat the field for each file, in the same order",
+                        "name": "alt_text",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}/files/{filename}": {
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Remove a file from a draft, queued or failed post and delete it, along with its alt text and user tags. A carousel left with one file becomes a feed post",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Remove a file from a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filename",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}/first_comment": {
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Set the comment posted on a post right after it is published, such as its hashtags. An empty comment means none is posted. The comment is held to the same limits as a caption",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Set a post's first comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "First comment",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.firstCommentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}/hashtag_sets": {
+            "get": {
+                "description": "Get the hashtag sets appended to a post when it is published",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hashtags"
+                ],
+                "summary": "Get a post's hashtag sets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Replace the hashtag sets appended to a post when it is published",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hashtags"
+                ],
+                "summary": "Set a post's hashtag sets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Hashtag set IDs",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.postHashtagSetsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}/images": {
+            "get": {
+                "description": "Get the alt text and user tags of each file of a post, in order",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "posts"
                 ],
-                "summary": "Get all posts",
+                "summary": "Get a post's image metadata",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Replace the alt text and user tags of the files of a post. Each image names one of the post's files; files left out get no metadata. User tag positions are fractions of the width and height from the top left corner",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Set a post's image metadata",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Image metadata",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.postImagesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}/location": {
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Tag a post with a location",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Set a post's location",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Location",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.locationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Remove the location a post is tagged with",
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Remove a post's location",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/posts/{id}/schedule": {
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Take a post out of the ordered queue and publish it at an exact time (RFC 3339)",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Pin a post to a publish time",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Publish time",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.pinPostRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Remove a post's publish time, returning it to the end of the ordered queue",
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Unpin a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "204": {
+                        "description": "No Content"
                     }
                 }
             }
         },
-        "/api/posts/clean_positions": {
+        "/api/posts/{id}/status": {
             "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Clean post positions",
+                "description": "Move a post through its lifecycle: draft, queued, posted, failed, interrupted, archived. Posts only enter and leave publishing through the publisher, so moving a post into or out of it is a conflict. When alt text is required, a post with a photo that has no alt text cannot be queued",
+                "consumes": [
+                    "application/json"
+                ],
                 "tags": [
                     "posts"
                 ],
-                "summary": "Clean post positions",
+                "summary": "Change a post's status",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.postStatusRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "204": {
                         "description": "No Content"
@@ -51,18 +1218,27 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/posts/make_post": {
+        "/api/posts/{id}/unpost": {
             "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Set a post as posted",
+                "description": "Move a posted post back to the end of the queue. When alt text is required, a post with a photo that has no alt text cannot be queued",
                 "tags": [
                     "posts"
                 ],
-                "summary": "Set a post as posted",
+                "summary": "Set a post as unposted",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "204": {
                         "description": "No Content"
@@ -70,20 +1246,144 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/posts/{id}": {
+        "/api/queue/projection": {
             "get": {
-                "description": "Get a post",
+                "description": "Get the expected publish time of every post in a queue, based on the queue's posting schedule and pinned times",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "queue"
                 ],
-                "summary": "Get a post",
+                "summary": "Get the queue projection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Queue to project: feed (the default) or story",
+                        "name": "queue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone to show times in, defaults to the instance time zone",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.queueProjectionResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/queue/status": {
+            "get": {
+                "description": "Get how many posts are queued, how far they reach at the posting schedule, and whether the queue is below the running-low threshold",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "Get the queue status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/alert.QueueStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get all webhook subscriptions, without their secrets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get all webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.webhookResponse"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Subscribe a URL to post lifecycle events. Each delivery is a JSON POST signed with HMAC-SHA256 of the body in the X-Isza-Signature header, and is retried with exponential backoff until it gets a 2xx response. Events: post.created, post.published, post.publish_failed, post.unposted, post.status_changed, post.deleted, post.files_changed, queue.reordered, queue.low, queue.recovered. The response holds the secret, which is not shown again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.createdWebhookResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a webhook subscription, without its secret",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get a webhook",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
+                        "description": "Webhook ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -91,7 +1391,48 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Update a webhook subscription; an empty secret keeps the current one",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Update a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
                     }
                 }
             },
@@ -101,15 +1442,15 @@ const docTemplate = `{
                         "Bearer": []
                     }
                 ],
-                "description": "Delete a post",
+                "description": "Delete a webhook subscription and its delivery log",
                 "tags": [
-                    "posts"
+                    "webhooks"
                 ],
-                "summary": "Delete a post",
+                "summary": "Delete a webhook",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
+                        "description": "Webhook ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -122,33 +1463,481 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/posts/{id}/unpost": {
-            "post": {
+        "/api/webhooks/{id}/deliveries": {
+            "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Set a post as unposted",
+                "description": "Get the delivery log of a webhook, newest first",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "posts"
+                    "webhooks"
                 ],
-                "summary": "Set a post as unposted",
+                "summary": "Get a webhook's deliveries",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
+                        "description": "Webhook ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of deliveries (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.webhookDeliveryResponse"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "alert.QueueStatus": {
+            "type": "object",
+            "properties": {
+                "coverage_days": {
+                    "type": "number"
+                },
+                "covered_until": {
+                    "description": "CoveredUntil is when the last queued post is expected to go out, or\nthe time of the check if the queue is empty.",
+                    "type": "string"
+                },
+                "held": {
+                    "description": "Held counts queued posts that blackouts keep from being placed on the\nschedule. They are left out of Queued and the coverage.",
+                    "type": "integer"
+                },
+                "low": {
+                    "type": "boolean"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "queued": {
+                    "type": "integer"
+                },
+                "threshold": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.blackoutRequest": {
+            "type": "object",
+            "properties": {
+                "ends_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "recurrence": {
+                    "type": "string",
+                    "enum": [
+                        "none",
+                        "weekly",
+                        "yearly"
+                    ]
+                },
+                "starts_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.captionVariableRequest": {
+            "type": "object",
+            "properties": {
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createdWebhookResponse": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.firstCommentRequest": {
+            "type": "object",
+            "properties": {
+                "first_comment": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.hashtagSetRequest": {
+            "type": "object",
+            "properties": {
+                "hashtags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.imageRequest": {
+            "type": "object",
+            "properties": {
+                "alt_text": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "user_tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.userTagRequest"
                     }
                 }
             }
+        },
+        "api.lintCaptionRequest": {
+            "type": "object",
+            "properties": {
+                "caption": {
+                    "type": "string"
+                },
+                "hashtag_set_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "post_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.locationRequest": {
+            "type": "object",
+            "properties": {
+                "lat": {
+                    "type": "number"
+                },
+                "lng": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.pinPostRequest": {
+            "type": "object",
+            "properties": {
+                "scheduled_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.postHashtagSetsRequest": {
+            "type": "object",
+            "properties": {
+                "hashtag_set_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "api.postImagesRequest": {
+            "type": "object",
+            "properties": {
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.imageRequest"
+                    }
+                }
+            }
+        },
+        "api.postStatusRequest": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.previewCaptionRequest": {
+            "type": "object",
+            "properties": {
+                "caption": {
+                    "type": "string"
+                },
+                "post_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.previewCaptionResponse": {
+            "type": "object",
+            "properties": {
+                "caption": {
+                    "type": "string"
+                },
+                "lint": {
+                    "$ref": "#/definitions/caption.Result"
+                }
+            }
+        },
+        "api.projectedPost": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string"
+                },
+                "pinned": {
+                    "type": "boolean"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "publish_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.queueProjectionResponse": {
+            "type": "object",
+            "properties": {
+                "display_time_zone": {
+                    "description": "DisplayTimeZone is the time zone of publish times and labels.",
+                    "type": "string"
+                },
+                "post_times": {
+                    "type": "string"
+                },
+                "posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.projectedPost"
+                    }
+                },
+                "queue": {
+                    "$ref": "#/definitions/repo.Queue"
+                },
+                "time_zone": {
+                    "description": "TimeZone is the instance time zone the post times are in.",
+                    "type": "string"
+                }
+            }
+        },
+        "api.reorderFilesRequest": {
+            "type": "object",
+            "properties": {
+                "filenames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.userTagRequest": {
+            "type": "object",
+            "properties": {
+                "username": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "number"
+                },
+                "y": {
+                    "type": "number"
+                }
+            }
+        },
+        "api.webhookDeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "description": "NextAttemptAt is null once the delivery has succeeded or given up.",
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "response_code": {
+                    "type": "integer"
+                },
+                "status": {
+                    "enum": [
+                        "pending",
+                        "succeeded",
+                        "failed"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/repo.DeliveryStatus"
+                        }
+                    ]
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.webhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Secret keys the HMAC-SHA256 signature. One is generated on create if\nempty, and kept on update if empty.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookResponse": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "caption.Issue": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "caption.Result": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/caption.Issue"
+                    }
+                },
+                "hashtags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "mentions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/caption.Issue"
+                    }
+                }
+            }
+        },
+        "repo.DeliveryStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "succeeded",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "DeliveryPending",
+                "DeliverySucceeded",
+                "DeliveryFailed"
+            ]
+        },
+        "repo.Queue": {
+            "type": "string",
+            "enum": [
+                "feed",
+                "story"
+            ],
+            "x-enum-varnames": [
+                "QueueFeed",
+                "QueueStory"
+            ]
         }
     },
     "securityDefinitions": {
@@ -168,7 +1957,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/",
 	Schemes:          []string{},
 	Title:            "An API",
-	Description:      "Nothing to see here",
+	Description:      "Nothing to see here. Mutating requests may carry an Idempotency-Key header; a retry with the same key and request gets the first response replayed, marked with Idempotent-Replayed: true",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
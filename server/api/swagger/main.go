@@ -2,7 +2,7 @@ package swagger
 
 // @title An API
 // @version 1.0
-// @description Nothing to see here
+// @description Nothing to see here. Mutating requests may carry an Idempotency-Key header; a retry with the same key and request gets the first response replayed, marked with Idempotent-Replayed: true
 // @BasePath /
 // @securityDefinitions.apikey Bearer
 // @in header
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/btschwartz12/isza/caption"
+	"github.com/btschwartz12/isza/repo"
+	"github.com/go-chi/chi/v5"
+)
+
+type captionVariableRequest struct {
+	Value string `json:"value"`
+}
+
+type previewCaptionRequest struct {
+	Caption string `json:"caption"`
+	PostID  int64  `json:"post_id"`
+}
+
+type previewCaptionResponse struct {
+	Caption string          `json:"caption"`
+	Lint    *caption.Result `json:"lint"`
+}
+
+// previewCaptionHandler godoc
+// @Summary Preview a caption
+// @Description Render a caption template as it would be published now, with the post's hashtag sets appended
+// @Tags captions
+// @Accept json
+// @Produce json
+// @Param request body previewCaptionRequest true "Caption template and optional post ID"
+// @Router /api/captions/preview [post]
+// @Success 200 {object} previewCaptionResponse
+func (s *ApiServer) previewCaptionHandler(w http.ResponseWriter, r *http.Request) {
+	var req previewCaptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	text, err := s.rpo.RenderCaption(r.Context(), s.instaUsername, req.PostID, req.Caption, time.Now())
+	if err != nil {
+		if errors.Is(err, caption.ErrInvalidCaption) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		s.log(r).Errorw("error rendering caption", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.MarshalIndent(previewCaptionResponse{
+		Caption: text,
+		Lint:    caption.Lint(text),
+	}, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling caption preview", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// getCaptionVariablesHandler godoc
+// @Summary Get caption variables
+// @Description Get the account's custom caption template variables
+// @Tags captions
+// @Produce json
+// @Router /api/caption_variables [get]
+// @Success 200
+func (s *ApiServer) getCaptionVariablesHandler(w http.ResponseWriter, r *http.Request) {
+	vars, err := s.rpo.GetCaptionVariables(r.Context(), s.instaUsername)
+	if err != nil {
+		s.log(r).Errorw("error getting caption variables", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.MarshalIndent(vars, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling caption variables", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// setCaptionVariableHandler godoc
+// @Summary Set a caption variable
+// @Description Create or update a custom caption template variable, referenced in captions as {{key}}
+// @Tags captions
+// @Accept json
+// @Param key path string true "Variable name"
+// @Param request body captionVariableRequest true "Variable value"
+// @Router /api/caption_variables/{key} [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) setCaptionVariableHandler(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	var req captionVariableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := s.rpo.SetCaptionVariable(r.Context(), s.instaUsername, key, req.Value)
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidVariable) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.log(r).Errorw("error setting caption variable", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("caption variable set", "key", key)
+}
+
+// deleteCaptionVariableHandler godoc
+// @Summary Delete a caption variable
+// @Description Delete a custom caption template variable
+// @Tags captions
+// @Param key path string true "Variable name"
+// @Router /api/caption_variables/{key} [delete]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) deleteCaptionVariableHandler(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	err := s.rpo.DeleteCaptionVariable(r.Context(), s.instaUsername, key)
+	if err != nil {
+		if errors.Is(err, repo.ErrVariableNotFound) {
+			http.Error(w, "Caption variable not found", http.StatusNotFound)
+			return
+		}
+		s.log(r).Errorw("error deleting caption variable", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("caption variable deleted", "key", key)
+}
@@ -0,0 +1,359 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/webhook"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// webhookResponse is a webhook as the API shows it, without its secret.
+type webhookResponse struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func newWebhookResponse(hook *repo.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    hook.Events,
+		Timestamp: hook.Timestamp,
+	}
+}
+
+// createdWebhookResponse is the response to creating a webhook, the only one
+// that shows its secret.
+type createdWebhookResponse struct {
+	webhookResponse
+	Secret string `json:"secret"`
+}
+
+type webhookDeliveryResponse struct {
+	ID           int64               `json:"id"`
+	WebhookID    int64               `json:"webhook_id"`
+	Event        string              `json:"event"`
+	Payload      string              `json:"payload"`
+	Status       repo.DeliveryStatus `json:"status" enums:"pending,succeeded,failed"`
+	Attempts     int64               `json:"attempts"`
+	ResponseCode int64               `json:"response_code"`
+	Error        string              `json:"error"`
+	// NextAttemptAt is null once the delivery has succeeded or given up.
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	Timestamp     time.Time  `json:"timestamp"`
+}
+
+type webhookRequest struct {
+	URL string `json:"url"`
+	// Secret keys the HMAC-SHA256 signature. One is generated on create if
+	// empty, and kept on update if empty.
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// getAllWebhooksHandler godoc
+// @Summary Get all webhooks
+// @Description Get all webhook subscriptions, without their secrets
+// @Tags webhooks
+// @Produce json
+// @Router /api/webhooks [get]
+// @Security Bearer
+// @Success 200 {array} webhookResponse
+func (s *ApiServer) getAllWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.rpo.GetAllWebhooks(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting all webhooks", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	hooksResp := make([]webhookResponse, len(hooks))
+	for i := range hooks {
+		hooksResp[i] = newWebhookResponse(&hooks[i])
+	}
+
+	resp, err := json.MarshalIndent(hooksResp, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling webhooks", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// getWebhookHandler godoc
+// @Summary Get a webhook
+// @Description Get a webhook subscription, without its secret
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Router /api/webhooks/{id} [get]
+// @Security Bearer
+// @Success 200 {object} webhookResponse
+func (s *ApiServer) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	hook, err := s.rpo.GetWebhook(r.Context(), id)
+	if err != nil {
+		if !writeWebhookError(w, err) {
+			s.log(r).Errorw("error getting webhook", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp, err := json.MarshalIndent(newWebhookResponse(hook), "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling webhook", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// createWebhookHandler godoc
+// @Summary Create a webhook
+// @Description Subscribe a URL to post lifecycle events. Each delivery is a JSON POST signed with HMAC-SHA256 of the body in the X-Isza-Signature header, and is retried with exponential backoff until it gets a 2xx response. Events: post.created, post.published, post.publish_failed, post.unposted, post.status_changed, post.deleted, post.files_changed, queue.reordered, queue.low, queue.recovered. The response holds the secret, which is not shown again
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param request body webhookRequest true "Webhook"
+// @Router /api/webhooks [post]
+// @Security Bearer
+// @Success 201 {object} createdWebhookResponse
+func (s *ApiServer) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			s.log(r).Errorw("error generating webhook secret", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		req.Secret = secret
+	}
+
+	hook, err := s.rpo.InsertWebhook(r.Context(), req.URL, req.Secret, req.Events)
+	if err != nil {
+		if !writeWebhookError(w, err) {
+			s.log(r).Errorw("error creating webhook", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp, err := json.MarshalIndent(createdWebhookResponse{
+		webhookResponse: newWebhookResponse(hook),
+		Secret:          hook.Secret,
+	}, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling webhook", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+	s.log(r).Infow("webhook created", "id", hook.ID, "events", hook.Events)
+}
+
+// updateWebhookHandler godoc
+// @Summary Update a webhook
+// @Description Update a webhook subscription; an empty secret keeps the current one
+// @Tags webhooks
+// @Accept json
+// @Param id path int true "Webhook ID"
+// @Param request body webhookRequest true "Webhook"
+// @Router /api/webhooks/{id} [put]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	req, ok := decodeWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Secret == "" {
+		hook, err := s.rpo.GetWebhook(r.Context(), id)
+		if err != nil {
+			if !writeWebhookError(w, err) {
+				s.log(r).Errorw("error getting webhook", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+		req.Secret = hook.Secret
+	}
+
+	err = s.rpo.UpdateWebhook(r.Context(), id, req.URL, req.Secret, req.Events)
+	if err != nil {
+		if !writeWebhookError(w, err) {
+			s.log(r).Errorw("error updating webhook", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("webhook updated", "id", id)
+}
+
+// deleteWebhookHandler godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook subscription and its delivery log
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Router /api/webhooks/{id} [delete]
+// @Security Bearer
+// @Success 204
+func (s *ApiServer) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.DeleteWebhook(r.Context(), id)
+	if err != nil {
+		if !writeWebhookError(w, err) {
+			s.log(r).Errorw("error deleting webhook", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	s.log(r).Infow("webhook deleted", "id", id)
+}
+
+// getWebhookDeliveriesHandler godoc
+// @Summary Get a webhook's deliveries
+// @Description Get the delivery log of a webhook, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "Maximum number of deliveries (default 50, max 500)"
+// @Router /api/webhooks/{id}/deliveries [get]
+// @Security Bearer
+// @Success 200 {array} webhookDeliveryResponse
+func (s *ApiServer) getWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := int64(defaultDeliveryLimit)
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	deliveries, err := s.rpo.GetWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		if !writeWebhookError(w, err) {
+			s.log(r).Errorw("error getting webhook deliveries", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	deliveriesResp := make([]webhookDeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		deliveriesResp[i] = webhookDeliveryResponse{
+			ID:            d.ID,
+			WebhookID:     d.WebhookID,
+			Event:         d.Event,
+			Payload:       d.Payload,
+			Status:        d.Status,
+			Attempts:      d.Attempts,
+			ResponseCode:  d.ResponseCode,
+			Error:         d.Error,
+			NextAttemptAt: d.NextAttemptAt.ToPointer(),
+			Timestamp:     d.Timestamp,
+		}
+	}
+
+	resp, err := json.MarshalIndent(deliveriesResp, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling webhook deliveries", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func decodeWebhookRequest(w http.ResponseWriter, r *http.Request) (*webhookRequest, bool) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if err := webhook.ValidateEvents(req.Events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeWebhookError writes the response for well-known webhook errors and
+// reports whether it did so.
+func writeWebhookError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, repo.ErrWebhookNotFound):
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+	case errors.Is(err, repo.ErrInvalidWebhook):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		return false
+	}
+	return true
+}
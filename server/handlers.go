@@ -1,18 +1,30 @@
 package server
 
 import (
+	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/btschwartz12/isza/alert"
 	"github.com/btschwartz12/isza/assets"
+	"github.com/btschwartz12/isza/calendar"
+	"github.com/btschwartz12/isza/caption"
+	"github.com/btschwartz12/isza/publisher"
 	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
+	"github.com/btschwartz12/isza/webhook"
 	"github.com/go-chi/chi/v5"
+	"github.com/samber/mo"
 )
 
 var (
-	homeTmpl = template.Must(template.ParseFS(
+	homeTmpl = template.Must(template.New("home.html.tmpl").Funcs(funcMap).ParseFS(
 		assets.Templates,
 		"templates/home.html.tmpl",
 	))
@@ -26,61 +38,217 @@ var (
 		"add1": func(i int) int {
 			return i + 1
 		},
+		"join":     strings.Join,
+		"isVideo":  repo.IsVideoFile,
+		"userTags": formatUserTags,
+		"datetimeLocal": func(t time.Time, loc *time.Location) string {
+			return t.In(loc).Format(datetimeLocalLayout)
+		},
+		"displayTime": func(t time.Time, loc *time.Location) string {
+			return t.In(loc).Format("2006-01-02 15:04:05 MST")
+		},
 	}
 
 	editPostTmpl = template.Must(template.New("editpost.html.tmpl").Funcs(funcMap).ParseFS(
 		assets.Templates,
 		"templates/editpost.html.tmpl",
 	))
+
+	calendarTmpl = template.Must(template.New("calendar.html.tmpl").Funcs(funcMap).ParseFS(
+		assets.Templates,
+		"templates/calendar.html.tmpl",
+	))
+
+	hashtagsTmpl = template.Must(template.New("hashtags.html.tmpl").Funcs(funcMap).ParseFS(
+		assets.Templates,
+		"templates/hashtags.html.tmpl",
+	))
+
+	blackoutsTmpl = template.Must(template.New("blackouts.html.tmpl").Funcs(funcMap).ParseFS(
+		assets.Templates,
+		"templates/blackouts.html.tmpl",
+	))
 )
 
+const (
+	// timeZoneCookie holds the IANA time zone the user sees times in.
+	timeZoneCookie = "tz"
+
+	// datetimeLocalLayout is the value format of datetime-local inputs.
+	datetimeLocalLayout = "2006-01-02T15:04"
+)
+
+// commonTimeZones are suggested in the time zone picker; any IANA name is
+// accepted.
+var commonTimeZones = []string{
+	"America/Los_Angeles",
+	"America/Denver",
+	"America/Chicago",
+	"America/New_York",
+	"UTC",
+	"Europe/London",
+	"Europe/Paris",
+	"Europe/Berlin",
+	"Europe/Athens",
+}
+
+type statusCount struct {
+	Status repo.PostStatus
+	Count  int
+}
+
+type calendarDay struct {
+	Date    time.Time
+	InRange bool
+	Today   bool
+	Events  []calendar.Event
+}
+
+type hashtagSetOption struct {
+	repo.HashtagSet
+	Selected bool
+}
+
+// displayLocation returns the time zone the user chose to see times in, or
+// the instance time zone if they have not chosen one.
+func (s *Server) displayLocation(r *http.Request) *time.Location {
+	if c, err := r.Cookie(timeZoneCookie); err == nil {
+		if loc, err := time.LoadLocation(c.Value); err == nil {
+			return loc
+		}
+	}
+	return s.sched.Location()
+}
+
+// dailyPostTimes describes the posting schedule, which is always in the
+// instance time zone.
+func (s *Server) dailyPostTimes() string {
+	return fmt.Sprintf("%s (%s)", s.sched, s.sched.Location())
+}
+
+// splitPinned separates the pinned posts of a projected queue from the rest.
+func splitPinned(projections []schedule.Projection) (pinned, queued []schedule.Projection) {
+	for _, p := range projections {
+		if p.Pinned {
+			pinned = append(pinned, p)
+		} else {
+			queued = append(queued, p)
+		}
+	}
+	return pinned, queued
+}
+
 func (s *Server) home(w http.ResponseWriter, r *http.Request) {
+	loc := s.displayLocation(r)
 	posts, err := s.rpo.GetAllPosts(r.Context())
 	if err != nil {
-		s.logger.Errorw("error getting all posts", "error", err)
+		s.log(r).Errorw("error getting all posts", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	var queuePosts []repo.Post
-	var stackPosts []repo.Post
+	var filter repo.PostStatus
+	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
+		filter, err = repo.ParseStatus(statusStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
+	byStatus := make(map[repo.PostStatus][]repo.Post)
 	for _, post := range posts {
-		if post.IsPosted {
-			stackPosts = append(stackPosts, post)
-		} else {
-			queuePosts = append(queuePosts, post)
-		}
+		byStatus[post.Status] = append(byStatus[post.Status], post)
 	}
 
-	sort.Slice(queuePosts, func(i, j int) bool {
-		return queuePosts[i].Position < queuePosts[j].Position
-	})
+	projections, err := schedule.ProjectQueue(r.Context(), s.rpo, repo.QueueFeed, s.sched, time.Now().In(loc))
+	if err != nil {
+		s.log(r).Errorw("error projecting queue", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	pinnedPosts, queuePosts := splitPinned(projections)
+
+	storyProjections, err := schedule.ProjectQueue(r.Context(), s.rpo, repo.QueueStory, s.storySched, time.Now().In(loc))
+	if err != nil {
+		s.log(r).Errorw("error projecting story queue", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	pinnedStories, queueStories := splitPinned(storyProjections)
+	stackPosts := byStatus[repo.StatusPosted]
+
+	blackouts, err := s.rpo.GetAllBlackouts(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting blackouts", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	blackout, resume, _ := s.sched.Paused(blackouts, time.Now())
+
+	queueStatus, err := s.monitor.Status(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting queue status", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
 
 	sort.Slice(stackPosts, func(i, j int) bool {
-		return stackPosts[i].PostedAt.MustGet().Time.After(stackPosts[j].PostedAt.MustGet().Time)
+		return stackPosts[i].PostedAt.MustGet().After(stackPosts[j].PostedAt.MustGet())
 	})
 
+	statusCounts := make([]statusCount, len(repo.AllStatuses))
+	for i, status := range repo.AllStatuses {
+		statusCounts[i] = statusCount{
+			Status: status,
+			Count:  len(byStatus[status]),
+		}
+	}
+
 	data := struct {
 		InstagramAccountURL string
-		DailyPostTimesEST   string
-		QueuePosts          []repo.Post
+		DailyPostTimes      string
+		DailyStoryTimes     string
+		TZ                  *time.Location
+		TimeZones           []string
+		StatusCounts        []statusCount
+		Filter              repo.PostStatus
+		FilteredPosts       []repo.Post
+		PinnedPosts         []schedule.Projection
+		QueuePosts          []schedule.Projection
+		PinnedStories       []schedule.Projection
+		QueueStories        []schedule.Projection
 		StackPosts          []repo.Post
+		Blackout            *repo.Blackout
+		ResumesAt           time.Time
+		QueueStatus         *alert.QueueStatus
 	}{
 		InstagramAccountURL: "https://instagram.com/youraccount", // Dummy variable
-		DailyPostTimesEST:   "12:00 PM, 6:00 PM",                 // Dummy variable
+		DailyPostTimes:      s.dailyPostTimes(),
+		DailyStoryTimes:     s.storySched.String(),
+		TZ:                  loc,
+		TimeZones:           commonTimeZones,
+		StatusCounts:        statusCounts,
+		Filter:              filter,
+		FilteredPosts:       byStatus[filter],
+		PinnedPosts:         pinnedPosts,
 		QueuePosts:          queuePosts,
+		PinnedStories:       pinnedStories,
+		QueueStories:        queueStories,
 		StackPosts:          stackPosts,
+		Blackout:            blackout,
+		ResumesAt:           resume,
+		QueueStatus:         queueStatus,
 	}
 
 	err = homeTmpl.Execute(w, data)
 	if err != nil {
-		s.logger.Errorw("error rendering home template", "error", err)
+		s.log(r).Errorw("error rendering home template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	s.logger.Infow("home page served")
+	s.log(r).Infow("home page served")
 }
 
 func (s *Server) editPostPage(w http.ResponseWriter, r *http.Request) {
@@ -98,14 +266,44 @@ func (s *Server) editPostPage(w http.ResponseWriter, r *http.Request) {
 
 	post, err := s.rpo.GetPost(r.Context(), id)
 	if err != nil {
-		s.logger.Errorw("error getting post", "error", err)
+		s.log(r).Errorw("error getting post", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	options, err := s.hashtagSetOptions(r, id)
+	if err != nil {
+		s.log(r).Errorw("error getting hashtag sets", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	err = editPostTmpl.Execute(w, post)
+	images, err := s.rpo.GetPostImages(r.Context(), id)
 	if err != nil {
-		s.logger.Errorw("error rendering edit post template", "error", err)
+		s.log(r).Errorw("error getting post images", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		*repo.Post
+		HashtagSets    []hashtagSetOption
+		Images         []repo.Image
+		MissingAltText bool
+		RequireAltText bool
+		TZ             *time.Location
+	}{
+		Post:           post,
+		HashtagSets:    options,
+		Images:         images,
+		MissingAltText: repo.MissingAltText(post.Type, images),
+		RequireAltText: s.rpo.RequiresAltText(),
+		TZ:             s.displayLocation(r),
+	}
+
+	err = editPostTmpl.Execute(w, data)
+	if err != nil {
+		s.log(r).Errorw("error rendering edit post template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -124,15 +322,84 @@ func (s *Server) editPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	caption := r.FormValue("caption")
-	if caption == "" {
+	post, err := s.rpo.GetPost(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repo.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		s.log(r).Errorw("error getting post", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	text := r.FormValue("caption")
+	if text == "" && post.Type != repo.TypeStory {
 		http.Error(w, "Caption is required", http.StatusBadRequest)
 		return
 	}
 
-	err = s.rpo.UpdatePostCaption(r.Context(), id, caption)
+	setIDs, err := parseHashtagSetIDs(r)
+	if err != nil {
+		http.Error(w, "Invalid hashtag set ID", http.StatusBadRequest)
+		return
+	}
+
+	if !s.checkCaption(w, r, text, setIDs) {
+		return
+	}
+
+	firstComment, ok := firstCommentValue(w, r)
+	if !ok {
+		return
+	}
+
+	images, err := parseImages(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	location, err := parseLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.rpo.SetPostImages(r.Context(), id, images); err != nil {
+		if errors.Is(err, repo.ErrInvalidMetadata) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.log(r).Errorw("error setting post images", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if err := s.rpo.SetPostLocation(r.Context(), id, location); err != nil {
+		if errors.Is(err, repo.ErrInvalidMetadata) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.log(r).Errorw("error setting post location", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err = s.rpo.UpdatePostCaption(r.Context(), id, text)
+	if err != nil {
+		s.log(r).Errorw("error updating post caption", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err = s.rpo.UpdatePostFirstComment(r.Context(), id, firstComment)
+	if err != nil {
+		s.log(r).Errorw("error updating post first comment", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err = s.rpo.SetPostHashtagSets(r.Context(), id, setIDs)
 	if err != nil {
-		s.logger.Errorw("error updating post caption", "error", err)
+		s.log(r).Errorw("error setting post hashtag sets", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -141,62 +408,190 @@ func (s *Server) editPostHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) addPostPage(w http.ResponseWriter, r *http.Request) {
-	err := addPostTmpl.Execute(w, nil)
+	sets, err := s.rpo.GetAllHashtagSets(r.Context())
 	if err != nil {
-		s.logger.Errorw("error rendering add post template", "error", err)
+		s.log(r).Errorw("error getting hashtag sets", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		HashtagSets      []repo.HashtagSet
+		MaxCarouselItems int
+		MaxUploadMb      int64
+		RequireAltText   bool
+	}{
+		HashtagSets:      sets,
+		MaxCarouselItems: s.rpo.MaxCarouselItems(),
+		MaxUploadMb:      s.maxUploadSize >> 20,
+		RequireAltText:   s.rpo.RequiresAltText(),
 	}
-	s.logger.Infow("add post page served")
+
+	err = addPostTmpl.Execute(w, data)
+	if err != nil {
+		s.log(r).Errorw("error rendering add post template", "error", err)
+	}
+	s.log(r).Infow("add post page served")
 }
 
 func (s *Server) uploadPostHandler(w http.ResponseWriter, r *http.Request) {
-	caption := r.FormValue("caption")
-	if caption == "" {
+	if !s.parseUpload(w, r) {
+		return
+	}
+
+	var postType repo.PostType
+	if t := r.FormValue("type"); t != "" {
+		var err error
+		postType, err = repo.ParsePostType(t)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Stories need no caption, but one given is checked all the same.
+	text := r.FormValue("caption")
+	if text == "" && postType != repo.TypeStory {
 		http.Error(w, "Caption is required", http.StatusBadRequest)
 		return
 	}
 
-	err := r.ParseMultipartForm(MaxPostUploadSize)
+	setIDs, err := parseHashtagSetIDs(r)
 	if err != nil {
-		s.logger.Errorw("error parsing form", "error", err)
-		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		http.Error(w, "Invalid hashtag set ID", http.StatusBadRequest)
 		return
 	}
 
-	files := make([]repo.UploadFile, 0)
-	for i := 1; i <= 5; i++ {
-		paramName := "file_" + string(rune('0'+i))
-		if fheaders, ok := r.MultipartForm.File[paramName]; ok && len(fheaders) > 0 {
-			fheader := fheaders[0]
-			file, err := fheader.Open()
-			if err != nil {
-				s.logger.Errorw("error opening file", "error", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				return
-			}
-			defer file.Close()
+	if !s.checkCaption(w, r, text, setIDs) {
+		return
+	}
+
+	firstComment, ok := firstCommentValue(w, r)
+	if !ok {
+		return
+	}
 
-			files = append(files, repo.UploadFile{
-				Header: fheader,
-				File:   &file,
-			})
+	// Files come in order in the repeated files field, or in the numbered
+	// file_1, file_2, ... fields older clients send. The repeated alt_text
+	// field, or alt_text_1, alt_text_2, ..., holds their alt text in the
+	// same order.
+	fheaders := r.MultipartForm.File["files"]
+	altTexts := r.MultipartForm.Value["alt_text"]
+	for i := 1; i <= s.rpo.MaxCarouselItems(); i++ {
+		if numbered := r.MultipartForm.File["file_"+strconv.Itoa(i)]; len(numbered) > 0 {
+			fheaders = append(fheaders, numbered[0])
+			altTexts = append(altTexts, r.FormValue("alt_text_"+strconv.Itoa(i)))
 		}
 	}
+	files := make([]repo.UploadFile, 0, len(fheaders))
+	for i, fheader := range fheaders {
+		file, err := fheader.Open()
+		if err != nil {
+			s.log(r).Errorw("error opening file", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+
+		var altText string
+		if i < len(altTexts) {
+			altText = altTexts[i]
+		}
+		files = append(files, repo.UploadFile{
+			Header:  fheader,
+			File:    &file,
+			AltText: altText,
+		})
+	}
 
 	if len(files) == 0 {
 		http.Error(w, "At least one file is required", http.StatusBadRequest)
 		return
 	}
 
-	_, err = s.rpo.InsertPost(r.Context(), caption, files)
+	var cover *repo.UploadFile
+	if fheaders := r.MultipartForm.File["cover"]; len(fheaders) > 0 {
+		file, err := fheaders[0].Open()
+		if err != nil {
+			s.log(r).Errorw("error opening file", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		cover = &repo.UploadFile{Header: fheaders[0], File: &file}
+	}
+
+	status := repo.StatusQueued
+	if r.FormValue("draft") != "" {
+		status = repo.StatusDraft
+	}
+
+	post, err := s.rpo.InsertPost(r.Context(), text, files, status, postType, cover, firstComment)
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidMedia) || errors.Is(err, repo.ErrInvalidExtension) ||
+			errors.Is(err, repo.ErrInvalidMetadata) || errors.Is(err, repo.ErrMissingAltText) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.log(r).Errorw("error inserting post", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err = s.rpo.SetPostHashtagSets(r.Context(), post.ID, setIDs)
 	if err != nil {
-		s.logger.Errorw("error inserting post", "error", err)
+		s.log(r).Errorw("error setting post hashtag sets", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	s.hooks.EmitPost(r.Context(), webhook.EventPostCreated, post, nil)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// checkCaption renders a caption and appends the hashtag sets with IDs
+// setIDs, as publishing will, then lints the result. It checks that every
+// variable the caption references is defined and that the sets exist. It
+// writes an error response and returns false if the caption is not valid.
+func (s *Server) checkCaption(w http.ResponseWriter, r *http.Request, text string, setIDs []int64) bool {
+	composed, err := s.rpo.ComposeCaption(r.Context(), s.pub.Username(), text, setIDs, time.Now())
+	if err != nil {
+		if errors.Is(err, caption.ErrInvalidCaption) || errors.Is(err, repo.ErrHashtagSetNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return false
+		}
+		s.log(r).Errorw("error composing caption", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+	// Stories need no caption.
+	if composed == "" {
+		return true
+	}
+	if res := caption.Lint(composed); !res.Valid() {
+		http.Error(w, res.Err().Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// parseUpload parses a multipart upload of at most s.maxUploadSize bytes. It
+// writes an error response and returns false if it cannot.
+func (s *Server) parseUpload(w http.ResponseWriter, r *http.Request) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadSize)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Upload is larger than %d MB", s.maxUploadSize>>20), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		s.log(r).Errorw("error parsing form", "error", err)
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 func (s *Server) movePostHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	if idStr == "" {
@@ -219,11 +614,247 @@ func (s *Server) movePostHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = s.rpo.MovePost(r.Context(), id, up)
 	if err != nil {
-		s.logger.Errorw("error moving post", "error", err)
+		if errors.Is(err, repo.ErrPostNotQueued) {
+			http.Error(w, "Only queued posts can be moved", http.StatusConflict)
+			return
+		}
+		s.log(r).Errorw("error moving post", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	s.hooks.EmitQueueReorderedForPost(r.Context(), id)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (s *Server) setPostStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	status, err := repo.ParseStatus(r.FormValue("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.SetPostStatus(r.Context(), id, status)
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidTransition) || errors.Is(err, repo.ErrMissingAltText) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		s.log(r).Errorw("error changing post status", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.hooks.EmitPostByID(r.Context(), webhook.EventPostStatusChanged, id)
+	http.Redirect(w, r, "/?status="+string(status), http.StatusSeeOther)
+}
+
+func (s *Server) schedulePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	scheduledAt := r.FormValue("scheduled_at")
+	if scheduledAt == "" {
+		err = s.rpo.UnpinPost(r.Context(), id)
+	} else {
+		at, perr := time.ParseInLocation(datetimeLocalLayout, scheduledAt, s.displayLocation(r))
+		if perr != nil {
+			http.Error(w, "Invalid scheduled time", http.StatusBadRequest)
+			return
+		}
+		err = s.rpo.PinPost(r.Context(), id, at)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrScheduleInThePast):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repo.ErrInvalidTransition):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			s.log(r).Errorw("error scheduling post", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.hooks.EmitQueueReorderedForPost(r.Context(), id)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (s *Server) retryCommentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.pub.RetryComment(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrPostNotFound):
+			http.Error(w, "Post not found", http.StatusNotFound)
+		case errors.Is(err, publisher.ErrNoComment), errors.Is(err, repo.ErrPostClaimed):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			s.log(r).Errorw("error retrying first comment", "error", err)
+			http.Error(w, "Comment failed: "+err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/post/%d/edit", id), http.StatusSeeOther)
+}
+
+func (s *Server) addPostFilesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	if !s.parseUpload(w, r) {
+		return
+	}
+	fheaders := r.MultipartForm.File["file"]
+	if len(fheaders) == 0 {
+		http.Error(w, "At least one file is required", http.StatusBadRequest)
+		return
+	}
+	// The repeated alt_text field holds the alt text of the files, in order.
+	altTexts := r.MultipartForm.Value["alt_text"]
+	files := make([]repo.UploadFile, 0, len(fheaders))
+	for i, fheader := range fheaders {
+		file, err := fheader.Open()
+		if err != nil {
+			s.log(r).Errorw("error opening file", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+
+		var altText string
+		if i < len(altTexts) {
+			altText = altTexts[i]
+		}
+		files = append(files, repo.UploadFile{Header: fheader, File: &file, AltText: altText})
+	}
+
+	post, err := s.rpo.AddPostFiles(r.Context(), id, files)
+	if err != nil {
+		s.writePostFilesError(w, r, err)
+		return
+	}
+	s.hooks.EmitPost(r.Context(), webhook.EventPostFilesChanged, post, nil)
+	http.Redirect(w, r, fmt.Sprintf("/post/%d/edit", id), http.StatusSeeOther)
+}
+
+func (s *Server) removePostFileHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	post, err := s.rpo.RemovePostFile(r.Context(), id, r.FormValue("filename"))
+	if err != nil {
+		s.writePostFilesError(w, r, err)
+		return
+	}
+	s.hooks.EmitPost(r.Context(), webhook.EventPostFilesChanged, post, nil)
+	http.Redirect(w, r, fmt.Sprintf("/post/%d/edit", id), http.StatusSeeOther)
+}
+
+func (s *Server) movePostFileHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		return
+	}
+
+	direction := r.FormValue("direction")
+	if direction != "up" && direction != "down" {
+		http.Error(w, "Invalid direction", http.StatusBadRequest)
+		return
+	}
+
+	post, err := s.rpo.GetPost(r.Context(), id)
+	if err != nil {
+		s.writePostFilesError(w, r, err)
+		return
+	}
+	filenames := post.ImageFilenames
+	i := slices.Index(filenames, r.FormValue("filename"))
+	if i < 0 {
+		http.Error(w, "Post has no such file", http.StatusBadRequest)
+		return
+	}
+	j := i - 1
+	if direction == "down" {
+		j = i + 1
+	}
+	if j >= 0 && j < len(filenames) {
+		filenames[i], filenames[j] = filenames[j], filenames[i]
+		post, err := s.rpo.ReorderPostFiles(r.Context(), id, filenames)
+		if err != nil {
+			s.writePostFilesError(w, r, err)
+			return
+		}
+		s.hooks.EmitPost(r.Context(), webhook.EventPostFilesChanged, post, nil)
+	}
+	http.Redirect(w, r, fmt.Sprintf("/post/%d/edit", id), http.StatusSeeOther)
+}
+
+func (s *Server) writePostFilesError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repo.ErrPostNotFound):
+		http.Error(w, "Post not found", http.StatusNotFound)
+	case errors.Is(err, repo.ErrInvalidMedia), errors.Is(err, repo.ErrInvalidExtension), errors.Is(err, repo.ErrInvalidMetadata):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repo.ErrMissingAltText):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, repo.ErrPostNotEditable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repo.ErrStorageFull):
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+	default:
+		s.log(r).Errorw("error changing post files", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) setTimeZoneHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.FormValue("tz"))
+	if name == "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:   timeZoneCookie,
+			Path:   "/",
+			MaxAge: -1,
+		})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if _, err := time.LoadLocation(name); err != nil {
+		http.Error(w, "Invalid time zone", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     timeZoneCookie,
+		Value:    name,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
@@ -236,3 +867,424 @@ func (s *Server) serveImageHandler(w http.ResponseWriter, r *http.Request) {
 	fullUrl := s.rpo.GetPathForPost(filename)
 	http.ServeFile(w, r, fullUrl)
 }
+
+func (s *Server) calendarPage(w http.ResponseWriter, r *http.Request) {
+	loc := s.displayLocation(r)
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	date := today
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		d, err := time.ParseInLocation("2006-01-02", dateStr, loc)
+		if err != nil {
+			http.Error(w, "Invalid date", http.StatusBadRequest)
+			return
+		}
+		date = d
+	}
+
+	view := r.URL.Query().Get("view")
+	var rangeStart, rangeEnd, prev, next time.Time
+	var title string
+	switch view {
+	case "week":
+		rangeStart = date.AddDate(0, 0, -int(date.Weekday()))
+		rangeEnd = rangeStart.AddDate(0, 0, 7)
+		prev, next = date.AddDate(0, 0, -7), date.AddDate(0, 0, 7)
+		title = "Week of " + rangeStart.Format("January 2, 2006")
+	case "", "month":
+		view = "month"
+		rangeStart = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, loc)
+		rangeEnd = rangeStart.AddDate(0, 1, 0)
+		prev, next = rangeStart.AddDate(0, -1, 0), rangeStart.AddDate(0, 1, 0)
+		title = rangeStart.Format("January 2006")
+	default:
+		http.Error(w, "Invalid view", http.StatusBadRequest)
+		return
+	}
+
+	// The grid always covers whole weeks, Sunday to Saturday.
+	gridStart := rangeStart.AddDate(0, 0, -int(rangeStart.Weekday()))
+	gridEnd := rangeEnd
+	if gridEnd.Weekday() != time.Sunday {
+		gridEnd = gridEnd.AddDate(0, 0, 7-int(gridEnd.Weekday()))
+	}
+
+	events, err := calendar.Events(r.Context(), s.rpo, s.sched, now)
+	if err != nil {
+		s.log(r).Errorw("error getting calendar events", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var days []calendarDay
+	for d := gridStart; d.Before(gridEnd); d = d.AddDate(0, 0, 1) {
+		day := calendarDay{
+			Date:    d,
+			InRange: !d.Before(rangeStart) && d.Before(rangeEnd),
+			Today:   d.Equal(today),
+		}
+		end := d.AddDate(0, 0, 1)
+		for _, e := range events {
+			if !e.Start.Before(d) && e.Start.Before(end) {
+				day.Events = append(day.Events, e)
+			}
+		}
+		days = append(days, day)
+	}
+
+	data := struct {
+		Title          string
+		View           string
+		Date           time.Time
+		Prev           time.Time
+		Next           time.Time
+		DailyPostTimes string
+		Weekdays       []string
+		Days           []calendarDay
+	}{
+		Title:          title,
+		View:           view,
+		Date:           date,
+		Prev:           prev,
+		Next:           next,
+		DailyPostTimes: s.dailyPostTimes(),
+		Weekdays:       []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		Days:           days,
+	}
+
+	err = calendarTmpl.Execute(w, data)
+	if err != nil {
+		s.log(r).Errorw("error rendering calendar template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) hashtagsPage(w http.ResponseWriter, r *http.Request) {
+	sets, err := s.rpo.GetAllHashtagSets(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting hashtag sets", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	usage, err := s.rpo.GetHashtagUsage(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting hashtag usage", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		HashtagSets []repo.HashtagSet
+		Usage       []repo.HashtagUsage
+	}{
+		HashtagSets: sets,
+		Usage:       usage,
+	}
+
+	err = hashtagsTmpl.Execute(w, data)
+	if err != nil {
+		s.log(r).Errorw("error rendering hashtags template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) createHashtagSetHandler(w http.ResponseWriter, r *http.Request) {
+	hashtags, err := caption.ParseHashtags(r.FormValue("hashtags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = s.rpo.InsertHashtagSet(r.Context(), strings.TrimSpace(r.FormValue("name")), hashtags)
+	if err != nil {
+		s.writeHashtagSetError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/hashtags", http.StatusSeeOther)
+}
+
+func (s *Server) editHashtagSetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid hashtag set ID", http.StatusBadRequest)
+		return
+	}
+
+	hashtags, err := caption.ParseHashtags(r.FormValue("hashtags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.UpdateHashtagSet(r.Context(), id, strings.TrimSpace(r.FormValue("name")), hashtags)
+	if err != nil {
+		s.writeHashtagSetError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/hashtags", http.StatusSeeOther)
+}
+
+func (s *Server) deleteHashtagSetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid hashtag set ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.DeleteHashtagSet(r.Context(), id)
+	if err != nil {
+		s.writeHashtagSetError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/hashtags", http.StatusSeeOther)
+}
+
+func (s *Server) writeHashtagSetError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repo.ErrHashtagSetNotFound):
+		http.Error(w, "Hashtag set not found", http.StatusNotFound)
+	case errors.Is(err, repo.ErrHashtagSetExists):
+		http.Error(w, "Hashtag set already exists", http.StatusConflict)
+	case errors.Is(err, repo.ErrInvalidHashtagSet):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		s.log(r).Errorw("error saving hashtag set", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) blackoutsPage(w http.ResponseWriter, r *http.Request) {
+	blackouts, err := s.rpo.GetAllBlackouts(r.Context())
+	if err != nil {
+		s.log(r).Errorw("error getting blackouts", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	active, resume, _ := s.sched.Paused(blackouts, time.Now())
+	data := struct {
+		Blackouts   []repo.Blackout
+		Recurrences []repo.Recurrence
+		Active      *repo.Blackout
+		ResumesAt   time.Time
+		TZ          *time.Location
+	}{
+		Blackouts:   blackouts,
+		Recurrences: []repo.Recurrence{repo.RecurNone, repo.RecurWeekly, repo.RecurYearly},
+		Active:      active,
+		ResumesAt:   resume,
+		TZ:          s.displayLocation(r),
+	}
+
+	err = blackoutsTmpl.Execute(w, data)
+	if err != nil {
+		s.log(r).Errorw("error rendering blackouts template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) createBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	start, end, recurrence, ok := parseBlackoutForm(w, r, s.displayLocation(r))
+	if !ok {
+		return
+	}
+
+	_, err := s.rpo.InsertBlackout(r.Context(), r.FormValue("name"), start, end, recurrence)
+	if err != nil {
+		s.writeBlackoutError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/blackouts", http.StatusSeeOther)
+}
+
+func (s *Server) editBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+
+	start, end, recurrence, ok := parseBlackoutForm(w, r, s.displayLocation(r))
+	if !ok {
+		return
+	}
+
+	err = s.rpo.UpdateBlackout(r.Context(), id, r.FormValue("name"), start, end, recurrence)
+	if err != nil {
+		s.writeBlackoutError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/blackouts", http.StatusSeeOther)
+}
+
+func (s *Server) deleteBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.rpo.DeleteBlackout(r.Context(), id)
+	if err != nil {
+		s.writeBlackoutError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/blackouts", http.StatusSeeOther)
+}
+
+func (s *Server) writeBlackoutError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repo.ErrBlackoutNotFound):
+		http.Error(w, "Blackout not found", http.StatusNotFound)
+	case errors.Is(err, repo.ErrInvalidBlackout):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		s.log(r).Errorw("error saving blackout", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func parseBlackoutForm(w http.ResponseWriter, r *http.Request, loc *time.Location) (time.Time, time.Time, repo.Recurrence, bool) {
+	start, err := time.ParseInLocation(datetimeLocalLayout, r.FormValue("starts_at"), loc)
+	if err != nil {
+		http.Error(w, "Invalid start time", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, "", false
+	}
+	end, err := time.ParseInLocation(datetimeLocalLayout, r.FormValue("ends_at"), loc)
+	if err != nil {
+		http.Error(w, "Invalid end time", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, "", false
+	}
+	recurrence, err := repo.ParseRecurrence(r.FormValue("recurrence"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return time.Time{}, time.Time{}, "", false
+	}
+	return start, end, recurrence, true
+}
+
+func (s *Server) hashtagSetOptions(r *http.Request, postID int64) ([]hashtagSetOption, error) {
+	sets, err := s.rpo.GetAllHashtagSets(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	selected, err := s.rpo.GetHashtagSetsForPost(r.Context(), postID)
+	if err != nil {
+		return nil, err
+	}
+	isSelected := make(map[int64]bool, len(selected))
+	for _, set := range selected {
+		isSelected[set.ID] = true
+	}
+	options := make([]hashtagSetOption, len(sets))
+	for i, set := range sets {
+		options[i] = hashtagSetOption{
+			HashtagSet: set,
+			Selected:   isSelected[set.ID],
+		}
+	}
+	return options, nil
+}
+
+// firstCommentValue returns the first_comment form field, which is held to
+// the same limits as a caption. It writes a 400 and returns false if the
+// comment breaks them.
+func firstCommentValue(w http.ResponseWriter, r *http.Request) (string, bool) {
+	comment := strings.TrimSpace(r.FormValue("first_comment"))
+	if res := caption.Lint(comment); comment != "" && !res.Valid() {
+		http.Error(w, "Invalid first comment: "+res.Err().Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return comment, true
+}
+
+// parseImages reads the metadata of each file of a post from the edit form,
+// which has an image, alt_text and user_tags field per file.
+func parseImages(r *http.Request) ([]repo.Image, error) {
+	filenames := r.Form["image"]
+	altTexts := r.Form["alt_text"]
+	userTags := r.Form["user_tags"]
+	if len(altTexts) != len(filenames) || len(userTags) != len(filenames) {
+		return nil, fmt.Errorf("each image needs alt text and user tags fields")
+	}
+	images := make([]repo.Image, len(filenames))
+	for i, filename := range filenames {
+		tags, err := parseUserTags(userTags[i])
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		images[i] = repo.Image{Filename: filename, AltText: altTexts[i], UserTags: tags}
+	}
+	return images, nil
+}
+
+// parseUserTags parses one user tag per line, as "username x y".
+func parseUserTags(s string) ([]repo.UserTag, error) {
+	tags := []repo.UserTag{}
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid user tag %q, expected \"username x y\"", strings.TrimSpace(line))
+		}
+		x, xerr := strconv.ParseFloat(fields[1], 64)
+		y, yerr := strconv.ParseFloat(fields[2], 64)
+		if xerr != nil || yerr != nil {
+			return nil, fmt.Errorf("invalid position in user tag %q", strings.TrimSpace(line))
+		}
+		tags = append(tags, repo.UserTag{Username: fields[0], X: x, Y: y})
+	}
+	return tags, nil
+}
+
+func formatUserTags(tags []repo.UserTag) string {
+	lines := make([]string, len(tags))
+	for i, tag := range tags {
+		lines[i] = fmt.Sprintf("%s %g %g", tag.Username, tag.X, tag.Y)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseLocation reads the location from the edit form. An empty name
+// removes it.
+func parseLocation(r *http.Request) (mo.Option[repo.Location], error) {
+	name := strings.TrimSpace(r.FormValue("location_name"))
+	if name == "" {
+		return mo.None[repo.Location](), nil
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("location_lat")), 64)
+	if err != nil {
+		return mo.None[repo.Location](), fmt.Errorf("invalid latitude")
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("location_lng")), 64)
+	if err != nil {
+		return mo.None[repo.Location](), fmt.Errorf("invalid longitude")
+	}
+	return mo.Some(repo.Location{Name: name, Lat: lat, Lng: lng}), nil
+}
+
+func parseHashtagSetIDs(r *http.Request) ([]int64, error) {
+	values := r.Form["hashtag_set"]
+	ids := make([]int64, len(values))
+	for i, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
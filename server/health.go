@@ -0,0 +1,34 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/btschwartz12/isza/health"
+)
+
+// healthzHandler reports that the process is alive and serving requests.
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}` + "\n"))
+}
+
+// readyzHandler reports whether everything needed to publish is working,
+// with 503 if anything is not.
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	report := s.health.Check(r.Context())
+
+	resp, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		s.log(r).Errorw("error marshalling readiness report", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != health.StatusOK {
+		s.log(r).Warnw("not ready", "report", report)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(resp)
+}
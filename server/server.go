@@ -1,50 +1,200 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
 
+	"github.com/btschwartz12/isza/alert"
+	"github.com/btschwartz12/isza/assets"
+	"github.com/btschwartz12/isza/health"
+	"github.com/btschwartz12/isza/idempotency"
+	"github.com/btschwartz12/isza/logging"
+	"github.com/btschwartz12/isza/metrics"
+	"github.com/btschwartz12/isza/notify"
+	"github.com/btschwartz12/isza/publisher"
 	"github.com/btschwartz12/isza/repo"
+	"github.com/btschwartz12/isza/schedule"
 	"github.com/btschwartz12/isza/server/api"
+	"github.com/btschwartz12/isza/tracing"
+	"github.com/btschwartz12/isza/webhook"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 )
 
 type Server struct {
 	router *chi.Mux
 	rpo    *repo.Repo
-	logger *zap.SugaredLogger
+	pub    *publisher.Publisher
+	sched  *schedule.Schedule
+	// storySched is the posting schedule of the story queue.
+	storySched *schedule.Schedule
+	hooks      *webhook.Dispatcher
+	monitor    *alert.Monitor
+	notifier   *notify.Notifier
+	health     *health.Checker
+	logger     *zap.SugaredLogger
+	// maxUploadSize is the most a request may upload, in bytes.
+	maxUploadSize int64
+	// ctx is cancelled on shutdown to stop the background loops.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-const (
-	MaxPostUploadMb   = 50
-	MaxPostUploadSize = MaxPostUploadMb << 20
-)
+// multipartMemory is how much of an upload is held in memory while it is
+// parsed; the rest goes to temporary files.
+const multipartMemory = 32 << 20
+
+// Config is how the server is set up.
+type Config struct {
+	VarDir          string
+	AuthToken       string
+	InstaUsername   string
+	InstaPassword   string
+	InstaWorkingDir string
+	// PostTimes and StoryTimes are the comma separated daily times of the
+	// feed and story queues.
+	PostTimes  string
+	StoryTimes string
+	TimeZone   string
+	// QueueLow is the threshold below which the queue is reported low.
+	QueueLow string
+	SMTP     notify.Config
+	// MetricsToken reads /metrics; empty means the API token does.
+	MetricsToken string
+	// CalendarToken reads the calendar feed; empty turns it off.
+	CalendarToken string
+	// MaxCarouselItems is the most files a carousel may have.
+	MaxCarouselItems int
+	// MaxUploadMb is the most a request may upload, in megabytes.
+	MaxUploadMb int
+	// RequireAltText keeps photos without alt text from being queued or
+	// published.
+	RequireAltText    bool
+	PublishTimeout    time.Duration
+	IdempotencyWindow time.Duration
+}
+
+// maxUploadSize returns the most a request may upload, in bytes.
+func (c Config) maxUploadSize() int64 {
+	return int64(c.MaxUploadMb) << 20
+}
 
-func (s *Server) Init(
-	logger *zap.SugaredLogger,
-	varDir,
-	authToken,
-	instaUsername,
-	instaPassword,
-	instaWorkingDir string,
-) error {
-	r, err := repo.NewRepo(logger, varDir)
+func (s *Server) Init(logger *zap.SugaredLogger, cfg Config) error {
+	if cfg.MaxUploadMb <= 0 {
+		return fmt.Errorf("max upload size must be positive, not %d MB", cfg.MaxUploadMb)
+	}
+	s.maxUploadSize = cfg.maxUploadSize()
+
+	loc, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		return fmt.Errorf("error loading time zone: %w", err)
+	}
+
+	r, err := repo.NewRepo(logger, cfg.VarDir, loc, repo.Options{
+		MaxCarouselItems: cfg.MaxCarouselItems,
+		RequireAltText:   cfg.RequireAltText,
+	})
 	if err != nil {
 		return fmt.Errorf("error creating repo: %w", err)
 	}
 	s.rpo = r
 	s.logger = logger
+
+	sched, err := schedule.Parse(cfg.PostTimes, loc)
+	if err != nil {
+		return fmt.Errorf("error parsing post times: %w", err)
+	}
+	s.sched = sched
+	s.storySched, err = schedule.Parse(cfg.StoryTimes, loc)
+	if err != nil {
+		return fmt.Errorf("error parsing story times: %w", err)
+	}
+
+	instaAbsDir, err := filepath.Abs(cfg.InstaWorkingDir)
+	if err != nil {
+		return fmt.Errorf("error getting absolute path for instagram working directory: %w", err)
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.hooks = webhook.New(logger, r)
+	go s.hooks.Run(s.ctx)
+
+	s.notifier, err = notify.New(logger, r, sched, cfg.SMTP)
+	if err != nil {
+		return fmt.Errorf("error creating notifier: %w", err)
+	}
+	go s.notifier.Run(s.ctx)
+
+	threshold, err := alert.ParseThreshold(cfg.QueueLow)
+	if err != nil {
+		return fmt.Errorf("error parsing queue threshold: %w", err)
+	}
+	s.monitor = alert.New(logger, r, sched, s.hooks, s.notifier, threshold)
+	go s.monitor.Run(s.ctx)
+
+	s.pub = publisher.New(logger, r, sched, s.hooks, s.notifier, cfg.InstaUsername, cfg.InstaPassword, instaAbsDir, cfg.PublishTimeout)
+	if err := s.pub.Recover(s.ctx); err != nil {
+		return fmt.Errorf("error recovering interrupted publishes: %w", err)
+	}
+	go s.pub.Run(s.ctx)
+
+	s.health = health.New(r, instaAbsDir)
+
 	s.router = chi.NewRouter()
+	s.router.Use(middleware.RequestID, tracing.Middleware, logging.Middleware(logger), metrics.Middleware)
+	// Metrics are never open; without a token of their own they take the
+	// API token.
+	metricsToken := cfg.MetricsToken
+	if metricsToken == "" {
+		metricsToken = cfg.AuthToken
+	}
+	s.router.Method(http.MethodGet, "/metrics", metrics.Handler(logger, r, metricsToken))
+	s.router.Get("/healthz", s.healthzHandler)
+	s.router.Get("/readyz", s.readyzHandler)
 	s.router.Get("/", s.home)
 	s.router.Get("/post", s.addPostPage)
-	s.router.Post("/post", s.uploadPostHandler)
+	s.router.With(idempotency.Middleware(logger, r, cfg.IdempotencyWindow, cfg.maxUploadSize())).Post("/post", s.uploadPostHandler)
 	s.router.Get("/post/{id}/edit", s.editPostPage)
 	s.router.Post("/post/{id}/edit", s.editPostHandler)
 	s.router.Get("/post/{id}/move", s.movePostHandler)
+	s.router.Post("/post/{id}/status", s.setPostStatusHandler)
+	s.router.Post("/post/{id}/schedule", s.schedulePostHandler)
+	s.router.Post("/post/{id}/comment/retry", s.retryCommentHandler)
+	s.router.Post("/post/{id}/files", s.addPostFilesHandler)
+	s.router.Post("/post/{id}/files/remove", s.removePostFileHandler)
+	s.router.Post("/post/{id}/files/move", s.movePostFileHandler)
+	s.router.Post("/timezone", s.setTimeZoneHandler)
+	s.router.Get("/calendar", s.calendarPage)
+	s.router.Get("/hashtags", s.hashtagsPage)
+	s.router.Post("/hashtags", s.createHashtagSetHandler)
+	s.router.Post("/hashtags/{id}/edit", s.editHashtagSetHandler)
+	s.router.Post("/hashtags/{id}/delete", s.deleteHashtagSetHandler)
+	s.router.Get("/blackouts", s.blackoutsPage)
+	s.router.Post("/blackouts", s.createBlackoutHandler)
+	s.router.Post("/blackouts/{id}/edit", s.editBlackoutHandler)
+	s.router.Post("/blackouts/{id}/delete", s.deleteBlackoutHandler)
 	s.router.Get("/static/posts/{filename}", s.serveImageHandler)
+	s.router.Handle("/static/js/*", http.FileServerFS(assets.Static))
 
 	apiServer := &api.ApiServer{}
-	err = apiServer.Init(logger, r, "/api", authToken, instaUsername, instaPassword, instaWorkingDir)
+	err = apiServer.Init(logger, api.Config{
+		Repo:              r,
+		Publisher:         s.pub,
+		Schedule:          sched,
+		StorySchedule:     s.storySched,
+		Hooks:             s.hooks,
+		Monitor:           s.monitor,
+		Notifier:          s.notifier,
+		Prefix:            "/api",
+		AuthToken:         cfg.AuthToken,
+		CalendarToken:     cfg.CalendarToken,
+		IdempotencyWindow: cfg.IdempotencyWindow,
+		MaxUploadSize:     cfg.maxUploadSize(),
+	})
 	if err != nil {
 		return fmt.Errorf("error initializing api server: %w", err)
 	}
@@ -55,3 +205,15 @@ func (s *Server) Init(
 func (s *Server) Router() chi.Router {
 	return s.router
 }
+
+// Shutdown stops the background loops and waits for publishes in progress
+// to finish, marking them as interrupted if ctx is done first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancel()
+	return s.pub.Drain(ctx)
+}
+
+// log returns the logger of the request, which carries its ID.
+func (s *Server) log(r *http.Request) *zap.SugaredLogger {
+	return logging.FromContext(r.Context(), s.logger)
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenDB opens a database like sql.Open, with a span for every query and
+// statement run through it with a traced context.
+func OpenDB(driverName, dsn string) (*sql.DB, error) {
+	return otelsql.Open(driverName, dsn,
+		otelsql.WithAttributes(semconv.DBSystemKey.String(driverName)),
+		otelsql.WithSpanNameFormatter(func(_ context.Context, method otelsql.Method, query string) string {
+			if query == "" {
+				return string(method)
+			}
+			return "db " + queryName(query)
+		}),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			// Only trace queries made on behalf of something already
+			// traced, rather than starting a trace for every background
+			// poll.
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			OmitConnectorConnect: true,
+		}),
+	)
+}
+
+// queryName returns the name sqlc gives a query in its leading
+// "-- name: GetPost :one" comment, or else the query's first keyword.
+func queryName(query string) string {
+	query = strings.TrimSpace(query)
+	if rest, ok := strings.CutPrefix(query, "-- name: "); ok {
+		name, _, _ := strings.Cut(rest, " ")
+		return name
+	}
+	op, _, _ := strings.Cut(query, " ")
+	return strings.ToUpper(op)
+}
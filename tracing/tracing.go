@@ -0,0 +1,92 @@
+// Package tracing sets up OpenTelemetry tracing, exported to a collector over
+// OTLP/HTTP. Until Init is called with an endpoint, the global tracer
+// provider is a no-op and spans cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracer starts isza's own spans. It follows the global tracer provider, so
+// it records once Init has set one up.
+var tracer = otel.Tracer("github.com/btschwartz12/isza")
+
+// provider is the tracer provider set up by Init, or nil.
+var provider *sdktrace.TracerProvider
+
+// Start starts a span as a child of the span in ctx, or as the root of a new
+// trace, and returns ctx carrying it.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it as failed with err if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Init starts exporting spans to the OTLP/HTTP collector at endpoint, e.g.
+// "http://localhost:4318", and continues the W3C trace context of incoming
+// requests. An empty endpoint leaves tracing disabled.
+func Init(ctx context.Context, logger *zap.SugaredLogger, endpoint, serviceName string) error {
+	if endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/traces"
+	}
+
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(u.String()))
+	if err != nil {
+		return fmt.Errorf("error creating otlp exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating trace resource: %w", err)
+	}
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warnw("tracing error", "error", err)
+	}))
+	logger.Infow("exporting traces", "endpoint", u.String())
+	return nil
+}
+
+// Shutdown stops recording spans and exports those still queued, until ctx
+// is done.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
@@ -0,0 +1,296 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/samber/mo"
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/isza/repo"
+)
+
+// Events a webhook can subscribe to.
+const (
+	EventPostCreated       = "post.created"
+	EventPostPublished     = "post.published"
+	EventPostPublishFailed = "post.publish_failed"
+	EventPostUnposted      = "post.unposted"
+	EventPostStatusChanged = "post.status_changed"
+	EventPostDeleted       = "post.deleted"
+	EventPostFilesChanged  = "post.files_changed"
+	EventQueueReordered    = "queue.reordered"
+	EventQueueLow          = "queue.low"
+	EventQueueRecovered    = "queue.recovered"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the request body, keyed
+	// with the webhook's secret, as "sha256=<hex>".
+	SignatureHeader = "X-Isza-Signature"
+	EventHeader     = "X-Isza-Event"
+	DeliveryHeader  = "X-Isza-Delivery"
+
+	// maxAttempts is how many times a delivery is tried before it is marked
+	// as failed.
+	maxAttempts = 6
+	// baseBackoff is the wait before the first retry; it doubles after every
+	// failed attempt.
+	baseBackoff = 30 * time.Second
+	// pollInterval is how often the dispatcher looks for due deliveries.
+	pollInterval = 5 * time.Second
+	// deliveryTimeout bounds a single delivery attempt.
+	deliveryTimeout = 10 * time.Second
+)
+
+var (
+	AllEvents = []string{
+		EventPostCreated,
+		EventPostPublished,
+		EventPostPublishFailed,
+		EventPostUnposted,
+		EventPostStatusChanged,
+		EventPostDeleted,
+		EventPostFilesChanged,
+		EventQueueReordered,
+		EventQueueLow,
+		EventQueueRecovered,
+	}
+
+	ErrUnknownEvent = fmt.Errorf("unknown webhook event")
+)
+
+// ValidateEvents checks that every name is a known event.
+func ValidateEvents(events []string) error {
+	for _, e := range events {
+		known := false
+		for _, k := range AllEvents {
+			if e == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: %q", ErrUnknownEvent, e)
+		}
+	}
+	return nil
+}
+
+// Payload is the JSON body sent to webhooks.
+type Payload struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// PostEvent is the data of post events.
+type PostEvent struct {
+	Post *repo.Post `json:"post"`
+	// Error is why publishing failed, for post.publish_failed.
+	Error string `json:"error,omitempty"`
+}
+
+// QueueEvent is the data of queue events.
+type QueueEvent struct {
+	Queue repo.Queue `json:"queue"`
+	// PostIDs is the ordered queue, front first.
+	PostIDs []int64 `json:"post_ids"`
+}
+
+// Sign returns the signature header value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher records events for subscribed webhooks and delivers them in the
+// background, retrying failures with exponential backoff.
+type Dispatcher struct {
+	logger *zap.SugaredLogger
+	rpo    *repo.Repo
+	client *http.Client
+	wake   chan struct{}
+}
+
+func New(logger *zap.SugaredLogger, rpo *repo.Repo) *Dispatcher {
+	return &Dispatcher{
+		logger: logger,
+		rpo:    rpo,
+		client: &http.Client{Timeout: deliveryTimeout},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Emit queues a delivery of event to every webhook subscribed to it. Errors
+// are logged rather than returned so that webhooks never fail the action
+// that caused the event.
+func (d *Dispatcher) Emit(ctx context.Context, event string, data any) {
+	hooks, err := d.rpo.GetAllWebhooks(ctx)
+	if err != nil {
+		d.logger.Errorw("error getting webhooks", "event", event, "error", err)
+		return
+	}
+	var body []byte
+	for _, hook := range hooks {
+		if !hook.Subscribes(event) {
+			continue
+		}
+		if body == nil {
+			body, err = json.Marshal(Payload{
+				Event:     event,
+				Timestamp: time.Now().UTC(),
+				Data:      data,
+			})
+			if err != nil {
+				d.logger.Errorw("error marshalling webhook payload", "event", event, "error", err)
+				return
+			}
+		}
+		if _, err := d.rpo.InsertWebhookDelivery(ctx, hook.ID, event, string(body)); err != nil {
+			d.logger.Errorw("error queueing webhook delivery", "webhook_id", hook.ID, "event", event, "error", err)
+		}
+	}
+	if body != nil {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// EmitPost emits a post event. cause is the error behind a failure event, or
+// nil.
+func (d *Dispatcher) EmitPost(ctx context.Context, event string, post *repo.Post, cause error) {
+	data := PostEvent{Post: post}
+	if cause != nil {
+		data.Error = cause.Error()
+	}
+	d.Emit(ctx, event, data)
+}
+
+// EmitPostByID emits a post event with the current state of the post.
+func (d *Dispatcher) EmitPostByID(ctx context.Context, event string, id int64) {
+	post, err := d.rpo.GetPost(ctx, id)
+	if err != nil {
+		d.logger.Errorw("error getting post for webhook", "id", id, "event", event, "error", err)
+		return
+	}
+	d.EmitPost(ctx, event, post, nil)
+}
+
+// EmitQueueReordered emits queue.reordered with the current order of queue.
+func (d *Dispatcher) EmitQueueReordered(ctx context.Context, queue repo.Queue) {
+	posts, err := d.rpo.GetUnpostedPosts(ctx, queue)
+	if err != nil {
+		d.logger.Errorw("error getting queue", "queue", queue, "error", err)
+		return
+	}
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	d.Emit(ctx, EventQueueReordered, QueueEvent{Queue: queue, PostIDs: ids})
+}
+
+// EmitQueueReorderedForPost emits queue.reordered for the queue of a post.
+func (d *Dispatcher) EmitQueueReorderedForPost(ctx context.Context, id int64) {
+	post, err := d.rpo.GetPost(ctx, id)
+	if err != nil {
+		d.logger.Errorw("error getting post for webhook", "id", id, "event", EventQueueReordered, "error", err)
+		return
+	}
+	d.EmitQueueReordered(ctx, post.Queue)
+}
+
+// Run delivers due deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		d.deliverDue(ctx)
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	deliveries, err := d.rpo.GetDueWebhookDeliveries(ctx, time.Now())
+	if err != nil {
+		d.logger.Errorw("error getting due webhook deliveries", "error", err)
+		return
+	}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		hook, err := d.rpo.GetWebhook(ctx, delivery.WebhookID)
+		if err != nil {
+			d.logger.Errorw("error getting webhook", "webhook_id", delivery.WebhookID, "error", err)
+			continue
+		}
+		d.attempt(ctx, hook, delivery)
+		if err := d.rpo.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			d.logger.Errorw("error recording webhook delivery", "id", delivery.ID, "error", err)
+		}
+	}
+}
+
+// attempt sends delivery once and updates it with the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, hook *repo.Webhook, delivery *repo.WebhookDelivery) {
+	delivery.Attempts++
+	code, err := d.send(ctx, hook, delivery)
+	delivery.ResponseCode = int64(code)
+	if err == nil {
+		delivery.Status = repo.DeliverySucceeded
+		delivery.Error = ""
+		delivery.NextAttemptAt = mo.None[time.Time]()
+		return
+	}
+
+	delivery.Error = err.Error()
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = repo.DeliveryFailed
+		delivery.NextAttemptAt = mo.None[time.Time]()
+		d.logger.Warnw("webhook delivery failed", "id", delivery.ID, "webhook_id", hook.ID, "attempts", delivery.Attempts, "error", err)
+		return
+	}
+	backoff := baseBackoff << (delivery.Attempts - 1)
+	delivery.NextAttemptAt = mo.Some(time.Now().Add(backoff))
+}
+
+func (d *Dispatcher) send(ctx context.Context, hook *repo.Webhook, delivery *repo.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "isza-webhooks")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}